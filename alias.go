@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ltsVersions 长期支持的主版本
+var ltsVersions = []string{"8", "11", "17", "21"}
+
+// constraints 可以代替具体版本的约束，按候选jdk在使用时解析
+var constraints = []string{"latest", "lts-latest", "lts"}
+
+var aliasNameRe = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_.-]*$`)
+
+// catalogJdks 当前系统可以下载的jdk key
+func catalogJdks() []string {
+	var keys []string
+	suffix := downloadKeyBy("", "")[1:]
+	for k := range jdks {
+		if strings.HasSuffix(k, suffix) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// versionNum 主版本号的数值，用于比较新旧
+func versionNum(key string) int {
+	n, _ := strconv.Atoi(strings.Split(key, "_")[1])
+	return n
+}
+
+// resolveConstraint 从 candidates 中选出满足约束的最新jdk，vendor 为空时不限厂商，优先默认厂商
+func resolveConstraint(c, vendor string, candidates []string) (string, bool) {
+	var ms []string
+	for _, k := range candidates {
+		ns := strings.Split(k, "_")
+		if vendor != "" && ns[0] != vendor {
+			continue
+		}
+		if c != "latest" && !contains(ltsVersions, ns[1]) {
+			continue
+		}
+		ms = append(ms, k)
+	}
+	if len(ms) == 0 {
+		return "", false
+	}
+	def := getSetting("default-vendor")
+	sort.Slice(ms, func(i, j int) bool {
+		if versionNum(ms[i]) != versionNum(ms[j]) {
+			return versionNum(ms[i]) > versionNum(ms[j])
+		}
+		return strings.HasPrefix(ms[i], def+"_") && !strings.HasPrefix(ms[j], def+"_")
+	})
+	return ms[0], true
+}
+
+// expandSelection 把别名、17.0.10-liberica 这类写法和 latest/lts-latest 约束展开为 <version> [vendor]，
+// candidates 为约束可以选择的jdk，没有满足约束的jdk时返回 false
+func expandSelection(subs []string, candidates []string) ([]string, bool) {
+	if len(subs) == 0 {
+		return subs, true
+	}
+	if t, ok := config.Aliases[subs[0]]; ok && len(subs) == 1 {
+		subs = strings.Fields(t)
+	}
+	if len(subs) == 1 && strings.ContainsAny(subs[0], ".-") && !contains(constraints, subs[0]) {
+		if vs := splitVendorVersion(subs[0]); len(vs) > 0 {
+			subs = vs
+		}
+	}
+	if !contains(constraints, subs[0]) {
+		return subs, true
+	}
+	vendor := ""
+	if len(subs) > 1 {
+		vendor = subs[1]
+	}
+	key, ok := resolveConstraint(subs[0], vendor, candidates)
+	if !ok {
+		return subs, false
+	}
+	ns := strings.Split(key, "_")
+	return []string{ns[1], ns[0]}, true
+}
+
+// aliasesOf 返回指向 key 的别名，约束别名按已安装的jdk解析
+func aliasesOf(key string) []string {
+	var names []string
+	for name, t := range config.Aliases {
+		subs, ok := expandSelection(strings.Fields(t), installedJdks())
+		if !ok || len(subs) == 0 {
+			continue
+		}
+		vendor := getSetting("default-vendor")
+		if len(subs) > 1 {
+			vendor = subs[1]
+		}
+		if downloadKeyBy(subs[0], vendor) == key {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// aliasSet 设置别名，别名可以用在 use、inst、cur、list 等接受版本的地方
+func aliasSet(subs []string) {
+	if len(subs) < 2 {
+		usagef("usage: jvm alias set <name> <version> [vendor]")
+		return
+	}
+	name := subs[0]
+	if err := validAliasName(name); err != nil {
+		usagef("%s", err)
+		return
+	}
+	target := strings.Join(subs[1:], " ")
+	if _, ok := config.Aliases[subs[1]]; ok {
+		usagef("%s is an alias, point %s at a version instead", subs[1], name)
+		return
+	}
+	// 约束在使用时才解析，这里只检查写法
+	if !contains(constraints, subs[1]) {
+		vs, _ := expandSelection(subs[1:], nil)
+		key, ok := parseVersionVendor(vs)
+		if !ok {
+			return
+		}
+		if patchTarget(subs[1], vs[0]) {
+			ns := strings.Split(key, "_")
+			color.Yellow("jvm keeps one patch release per major version, %s resolves to %s [%s]", subs[1], ns[1], ns[0])
+		}
+	} else if len(subs) > 2 && !contains(selectVendor, subs[2]) {
+		usagef("un support jdk type:%s", subs[2])
+		return
+	}
+	if config.Aliases == nil {
+		config.Aliases = map[string]string{}
+	}
+	config.Aliases[name] = target
+	color.Green("%s -> %s", name, target)
+}
+
+func aliasRm(subs []string) {
+	if len(subs) < 1 {
+		usagef("usage: jvm alias rm <name>")
+		return
+	}
+	if _, ok := config.Aliases[subs[0]]; !ok {
+		usagef("no alias named %s", subs[0])
+		return
+	}
+	delete(config.Aliases, subs[0])
+	color.Green("alias %s removed", subs[0])
+}
+
+func aliasList(subs []string) {
+	var names []string
+	for name := range config.Aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		t := config.Aliases[name]
+		vs, ok := expandSelection(strings.Fields(t), installedJdks())
+		if !ok {
+			color.Yellow("  %-12s -> %s (nothing installed matches)", name, t)
+			continue
+		}
+		// 手动修改或旧版本留下的别名可能已经无效
+		if len(vs) == 0 || !contains(supportVersion, vs[0]) || len(vs) > 1 && !contains(selectVendor, vs[1]) {
+			color.Red("  %-12s -> %s (invalid, use [jvm alias set %s <version>] or [jvm alias rm %s])", name, t, name, name)
+			continue
+		}
+		vendor := getSetting("default-vendor")
+		if len(vs) > 1 {
+			vendor = vs[1]
+		}
+		color.Blue("  %-12s -> %s (%s [%s])", name, t, vs[0], vendor)
+	}
+}
+
+// patchTarget 别名目标是否写了补丁版本，如 17.0.10-liberica
+func patchTarget(target, major string) bool {
+	for _, p := range strings.Split(target, "-") {
+		if p != "" && p[0] >= '0' && p[0] <= '9' && p != major {
+			return true
+		}
+	}
+	return false
+}
+
+// validAliasName 别名不能与版本、厂商或约束混淆
+func validAliasName(name string) error {
+	if !aliasNameRe.MatchString(name) {
+		return fmt.Errorf("alias must start with a letter and contain only letters, digits, '.', '_' or '-'")
+	}
+	if contains(selectVendor, name) || contains(constraints, name) {
+		return fmt.Errorf("%s is reserved", name)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type tarEntry struct {
+	name, link, body string
+}
+
+func writeTarball(t *testing.T, path string, entries []tarEntry) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for _, e := range entries {
+		h := &tar.Header{Name: e.name, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(e.body))}
+		if e.link != "" {
+			h = &tar.Header{Name: e.name, Mode: 0777, Typeflag: tar.TypeSymlink, Linkname: e.link}
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+	gw.Close()
+}
+
+func TestExtractRelevantDirs(t *testing.T) {
+	java := tarEntry{name: "jdk-17/bin/java", body: "java"}
+	tests := []struct {
+		name    string
+		entries []tarEntry
+		wantErr bool
+	}{
+		{"plain", []tarEntry{java, {name: "jdk-17/lib/a", body: "a"}}, false},
+		{"macos root", []tarEntry{{name: "./jdk-17.jdk/Contents/Home/bin/java", body: "java"}, {name: "./jdk-17.jdk/Contents/Info.plist", body: "x"}}, false},
+		{"relative link inside", []tarEntry{java, {name: "jdk-17/legal/a", body: "a"}, {name: "jdk-17/legal/b", link: "a"}}, false},
+		{"dot dot path", []tarEntry{java, {name: "jdk-17/../../evil", body: "x"}}, true},
+		{"absolute link", []tarEntry{java, {name: "jdk-17/x", link: "/etc"}}, true},
+		{"link out of the jdk", []tarEntry{java, {name: "jdk-17/x", link: "../../.."}, {name: "jdk-17/x/evil", body: "x"}}, true},
+		{"write through link", []tarEntry{java, {name: "jdk-17/q", link: "."}, {name: "jdk-17/p", link: "q/.."}, {name: "jdk-17/p/evil", body: "x"}}, true},
+		{"file over link", []tarEntry{java, {name: "jdk-17/lib", link: "bin"}, {name: "jdk-17/lib", body: "x"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tarball := filepath.Join(dir, "jdk.tar.gz")
+			writeTarball(t, tarball, tt.entries)
+			target := filepath.Join(dir, "a", "b", "jdk")
+			if err := os.MkdirAll(target, 0755); err != nil {
+				t.Fatal(err)
+			}
+			err := extractRelevantDirs(tarball, target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !pathExist(filepath.Join(target, "bin", "java")) {
+				t.Errorf("bin/java not extracted")
+			}
+			for _, p := range []string{filepath.Join(dir, "evil"), filepath.Join(dir, "a", "evil"), filepath.Join(dir, "a", "b", "evil")} {
+				if pathExist(p) {
+					t.Errorf("%s written outside the jdk", p)
+				}
+			}
+		})
+	}
+}
+
+func TestUnzipJDK(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []string
+		wantErr bool
+	}{
+		{"plain", []string{"jdk-17/bin/", "jdk-17/bin/java.exe", "jdk-17/lib/a"}, false},
+		{"no bin", []string{"jdk-17/lib/a"}, true},
+		{"zip slip", []string{"jdk-17/bin/", "jdk-17/bin/java.exe", "jdk-17/../../evil"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src := filepath.Join(dir, "jdk.zip")
+			f, err := os.Create(src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			zw := zip.NewWriter(f)
+			for _, n := range tt.files {
+				w, err := zw.Create(n)
+				if err != nil {
+					t.Fatal(err)
+				}
+				w.Write([]byte(n))
+			}
+			zw.Close()
+			f.Close()
+			dest := filepath.Join(dir, "a", "jdk")
+			if err := os.MkdirAll(dest, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := unzipJDK(src, dest); (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if pathExist(filepath.Join(dir, "evil")) {
+				t.Errorf("evil written outside the jdk")
+			}
+		})
+	}
+}
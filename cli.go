@@ -0,0 +1,364 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/fatih/color"
+	"io"
+	"os"
+	"strings"
+)
+
+// 退出码，脚本可以据此区分失败原因
+const (
+	exitOK           = 0
+	exitFailure      = 1
+	exitUsage        = 2
+	exitNetwork      = 3
+	exitVerify       = 4 // 留给安装包的校验和验证，目前没有校验和可以比较
+	exitNotInstalled = 5
+)
+
+// exitNames json 错误输出中退出码对应的类型
+var exitNames = map[int]string{
+	exitFailure:      "failure",
+	exitUsage:        "usage",
+	exitNetwork:      "network",
+	exitVerify:       "verification",
+	exitNotInstalled: "not-installed",
+}
+
+// exitCode 命令结束后进程的退出码
+var exitCode = exitOK
+
+// codedError 带退出码的错误
+type codedError struct {
+	code int
+	err  error
+}
+
+func (e *codedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *codedError) Unwrap() error {
+	return e.err
+}
+
+// withCode 给错误加上退出码，err 为 nil 时返回 nil
+func withCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &codedError{code: code, err: err}
+}
+
+// failErr 输出并记录错误，退出码取自 codedError，默认为 exitFailure
+func failErr(err error) {
+	code := exitFailure
+	var ce *codedError
+	if errors.As(err, &ce) {
+		code = ce.code
+	}
+	cmdErr = err
+	exitCode = code
+	color.Red("%s", err)
+}
+
+// failCode 按 code 输出并记录错误
+func failCode(code int, format string, a ...interface{}) {
+	failErr(withCode(code, fmt.Errorf(format, a...)))
+}
+
+// failf 输出并记录一般错误
+func failf(format string, a ...interface{}) {
+	failCode(exitFailure, format, a...)
+}
+
+// usagef 输出并记录用法错误
+func usagef(format string, a ...interface{}) {
+	failCode(exitUsage, format, a...)
+}
+
+// 命令参数，由各命令的 flags 绑定
+var (
+	flagVendor     string
+	flagArch       string
+	flagForce      bool
+	flagKeep       bool
+	flagDryRun     bool
+	flagSystem     bool
+	flagShell      string
+	flagHook       bool
+	flagInstall    bool
+	flagNoDownload bool
+	flagCompletion bool
+)
+
+func vendorFlag(fs *flag.FlagSet) {
+	fs.StringVar(&flagVendor, "vendor", "", "jdk vendor "+strings.Join(selectVendor, "|")+", same as the [vendor] argument")
+}
+
+func archFlag(fs *flag.FlagSet) {
+	fs.StringVar(&flagArch, "arch", "", "cpu architecture x64|arch64|x32|arch32, default the one of this system")
+}
+
+// archAliases --arch 的写法与jdk key中架构的对应关系
+var archAliases = map[string]string{
+	"x64":     "x64",
+	"amd64":   "x64",
+	"x86_64":  "x64",
+	"arch64":  "arch64",
+	"arm64":   "arch64",
+	"aarch64": "arch64",
+	"x32":     "x32",
+	"386":     "x32",
+	"arch32":  "arch32",
+	"arm":     "arch32",
+}
+
+// parseArgs 解析命令参数，参数可以出现在位置参数之间，-- 之后的内容原样保留在位置参数中
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var tail []string
+	for i, a := range args {
+		if a == "--" {
+			args, tail = args[:i], args[i:]
+			break
+		}
+	}
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		pos = append(pos, args[0])
+		args = args[1:]
+	}
+	return append(pos, tail...), nil
+}
+
+func newFlagSet(cmd CmdInfo) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if cmd.flags != nil {
+		cmd.flags(fs)
+	}
+	return fs
+}
+
+func findCmd(cmds []CmdInfo, name string) (CmdInfo, bool) {
+	for _, c := range cmds {
+		if c.cmd == name {
+			return c, true
+		}
+	}
+	return CmdInfo{}, false
+}
+
+// dispatch 执行 args[1] 对应的命令，有子命令时继续按下一个参数查找
+func dispatch(args []string) {
+	if len(args) <= 1 {
+		help(commands)
+		return
+	}
+	mc := args[1]
+	if mc == "help" || mc == "--help" || mc == "-h" {
+		helpCmd(args[2:])
+		return
+	}
+	// 补全需要读取命令表，且不能受命令参数解析的影响
+	if mc == completeCmd {
+		completeJdk(args[2:])
+		return
+	}
+	cmd, ok := findCmd(commands, mc)
+	if !ok {
+		usagef("unknown command:%s, use [jvm help] for all commands", mc)
+		return
+	}
+	path := []string{cmd.cmd}
+	rest := args[2:]
+	for len(cmd.subs) > 0 {
+		if len(rest) == 0 || strings.HasPrefix(rest[0], "-") {
+			if contains(rest, "--help") || contains(rest, "-h") {
+				cmdHelp(path, cmd)
+				return
+			}
+			usagef("jvm %s needs one of %s, use [jvm help %s] for detail", strings.Join(path, " "), subNames(cmd), strings.Join(path, " "))
+			return
+		}
+		sub, ok := findCmd(cmd.subs, rest[0])
+		if !ok {
+			usagef("un support %s action:%s, one of %s", strings.Join(path, " "), rest[0], subNames(cmd))
+			return
+		}
+		// 子命令继承父命令的锁和是否需要先执行 jvm on
+		sub.lock = sub.lock || cmd.lock
+		sub.always = sub.always || cmd.always
+		path = append(path, sub.cmd)
+		cmd, rest = sub, rest[1:]
+	}
+
+	if !cmd.always && !config.Enabled {
+		failf("jvm is not enabled, use [jvm on] to enable java version manager")
+		return
+	}
+	if jsonOutput() && !cmd.json {
+		usagef("--json is not supported by jvm %s", strings.Join(path, " "))
+		return
+	}
+	pos, err := parseArgs(newFlagSet(cmd), rest)
+	if err == flag.ErrHelp {
+		cmdHelp(path, cmd)
+		return
+	}
+	if err != nil {
+		usagef("jvm %s: %s, use [jvm help %s] for detail", strings.Join(path, " "), err, strings.Join(path, " "))
+		return
+	}
+	if flagVendor != "" && !contains(selectVendor, flagVendor) {
+		usagef("un support jdk type:%s, one of %s", flagVendor, strings.Join(selectVendor, "|"))
+		return
+	}
+	if flagArch != "" {
+		a, ok := archAliases[flagArch]
+		if !ok {
+			usagef("un support arch:%s, one of x64|arch64|x32|arch32", flagArch)
+			return
+		}
+		flagArch = a
+	}
+	if !cmd.lock {
+		cmd.proc(pos)
+		return
+	}
+	if err := withStateLock(func() { cmd.proc(pos) }); err != nil {
+		failErr(err)
+	}
+}
+
+func subNames(cmd CmdInfo) string {
+	var ns []string
+	for _, s := range cmd.subs {
+		ns = append(ns, s.cmd)
+	}
+	return strings.Join(ns, "|")
+}
+
+// help 输出所有命令的概要
+func help(commands []CmdInfo) {
+	color.White("Usage: jvm <command> [args] [flags]")
+	color.White("")
+	var cmdLen = -1
+	for _, cmd := range commands {
+		if cmd.hidden {
+			continue
+		}
+		l := len(fmt.Sprintf("  jvm %s  ", cmd.cmd))
+		if l > cmdLen {
+			cmdLen = l
+		}
+	}
+	for _, cmd := range commands {
+		if cmd.hidden {
+			continue
+		}
+		c := fmt.Sprintf("  jvm %s", cmd.cmd)
+		c = c + strings.Repeat(" ", cmdLen-len(c))
+		color.New(color.FgCyan).Print(c)
+		color.White(" : %s", strings.Split(cmd.desc, "\n")[0])
+	}
+	color.White("")
+	color.White("Global flags:")
+	color.White("  --json, --format text|json  print the result of list, cur, ls-remote, info, inst, outdated, upgrade and doctor as json,")
+	color.White("                              {\"schema\":%d,\"kind\":\"<command>\",\"data\":{...}} or {\"schema\":%d,\"kind\":\"error\",\"error\":{...}}", schemaVersion, schemaVersion)
+	color.White("")
+	color.White("Exit codes: 0 ok, 1 failure, 2 usage error, 3 network failure, 5 jdk not installed, 4 is reserved for checksum verification")
+	color.White("Use [jvm help <command>] for the flags and examples of a command.")
+}
+
+// helpCmd jvm help [command [sub]]
+func helpCmd(subs []string) {
+	if len(subs) == 0 {
+		help(commands)
+		return
+	}
+	cmd, ok := findCmd(commands, subs[0])
+	if !ok {
+		usagef("unknown command:%s, use [jvm help] for all commands", subs[0])
+		return
+	}
+	path := []string{cmd.cmd}
+	for _, s := range subs[1:] {
+		sub, ok := findCmd(cmd.subs, s)
+		if !ok {
+			break
+		}
+		cmd = sub
+		path = append(path, sub.cmd)
+	}
+	cmdHelp(path, cmd)
+}
+
+// cmdHelp 输出一个命令的用法、参数、子命令和示例
+func cmdHelp(path []string, cmd CmdInfo) {
+	usage := "jvm " + strings.Join(path, " ")
+	if len(cmd.subs) > 0 {
+		usage += " <" + subNames(cmd) + ">"
+	}
+	if cmd.args != "" {
+		usage += " " + cmd.args
+	}
+	fs := newFlagSet(cmd)
+	hasFlags := false
+	fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		usage += " [flags]"
+	}
+	color.White("Usage: %s", usage)
+	color.White("")
+	for _, line := range strings.Split(cmd.desc, "\n") {
+		color.White("  %s", line)
+	}
+	if len(cmd.subs) > 0 {
+		color.White("")
+		color.White("Commands:")
+		for _, s := range cmd.subs {
+			color.New(color.FgCyan).Printf("  %-30s", strings.TrimSpace(s.cmd+" "+s.args))
+			color.White(" %s", strings.Split(s.desc, "\n")[0])
+		}
+	}
+	if hasFlags {
+		color.White("")
+		color.White("Flags:")
+		fs.VisitAll(func(f *flag.Flag) {
+			name, u := flag.UnquoteUsage(f)
+			color.New(color.FgCyan).Printf("  --%-16s", strings.TrimSpace(f.Name+" "+name))
+			color.White(" %s", u)
+		})
+	}
+	if len(cmd.examples) > 0 {
+		color.White("")
+		color.White("Examples:")
+		for _, e := range cmd.examples {
+			color.White("  %s", e)
+		}
+	}
+}
+
+// exitWith 释放资源后按命令结果退出
+func exitWith() {
+	if cmdErr == nil {
+		return
+	}
+	if jsonOutput() {
+		printJSONError(cmdErr)
+	}
+	exit()
+	os.Exit(exitCode)
+}
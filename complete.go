@@ -0,0 +1,277 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// completeCmd 补全脚本调用的隐藏命令，jvm __complete -- <已输入的词> <正在输入的词>
+const completeCmd = "__complete"
+
+// supportCompletion 可以生成补全脚本的shell
+var supportCompletion = []string{"bash", "zsh", "fish"}
+
+const bashCompletion = `# jvm completion for bash, load with: eval "$(jvm completion bash)"
+_jvm() {
+    local cur=${COMP_WORDS[COMP_CWORD]} c IFS=$'\n'
+    COMPREPLY=()
+    for c in $(jvm ` + completeCmd + ` -- "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null); do
+        c=${c%%$'\t'*}
+        [[ $c == "$cur"* ]] && COMPREPLY+=("$c")
+    done
+}
+complete -o default -F _jvm jvm
+`
+
+const zshCompletion = `# jvm completion for zsh, load with: eval "$(jvm completion zsh)"
+_jvm() {
+    local -a cands
+    cands=("${(@f)$(jvm ` + completeCmd + ` -- "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    cands=("${(@)cands//:/\\:}")
+    cands=("${(@)cands//$'\t'/:}")
+    if [[ -n ${cands[1]} ]]; then
+        _describe jvm cands
+    else
+        _files
+    fi
+}
+if (( ! $+functions[compdef] )); then
+    autoload -Uz compinit && compinit
+fi
+compdef _jvm jvm
+`
+
+const fishCompletion = `# jvm completion for fish, load with: jvm completion fish | source
+function __jvm_complete
+    set -l words (commandline -opc) (commandline -ct)
+    jvm ` + completeCmd + ` -- $words[2..-1] 2>/dev/null
+end
+complete -c jvm -f -a '(__jvm_complete)'
+`
+
+// completionJdk 输出shell的补全脚本，候选项在补全时通过 jvm __complete 动态生成
+func completionJdk(subs []string) {
+	shell := detectShell()
+	if len(subs) > 0 {
+		shell = subs[0]
+	}
+	switch shell {
+	case "bash":
+		fmt.Print(bashCompletion)
+	case "zsh":
+		fmt.Print(zshCompletion)
+	case "fish":
+		fmt.Print(fishCompletion)
+	default:
+		usagef("un support shell:%s, one of %s", shell, strings.Join(supportCompletion, "|"))
+	}
+}
+
+// completeJdk 按已输入的词输出候选项，每行一个，tab 后为说明
+func completeJdk(subs []string) {
+	if len(subs) > 0 && subs[0] == "--" {
+		subs = subs[1:]
+	}
+	if len(subs) == 0 {
+		subs = []string{""}
+	}
+	for _, c := range completeWords(subs[:len(subs)-1], subs[len(subs)-1]) {
+		fmt.Println(c)
+	}
+}
+
+// completeWords 根据命令表解析 words 得到当前的命令、参数和位置参数，返回 cur 的候选项
+func completeWords(words []string, cur string) []string {
+	var cmd *CmdInfo
+	var path []string
+	var pos []string
+	var fs *flag.FlagSet
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		switch {
+		case w == "--":
+			// -- 之后是 exec 执行的命令，交给shell默认补全
+			return nil
+		case w == "--format":
+			i++
+		case strings.HasPrefix(w, "-"):
+			if fs != nil && takesValue(fs, w) {
+				i++
+			}
+		case cmd == nil:
+			c, ok := findCmd(commands, w)
+			if !ok {
+				return nil
+			}
+			cmd, path, fs = &c, []string{w}, newFlagSet(c)
+		case len(cmd.subs) > 0:
+			c, ok := findCmd(cmd.subs, w)
+			if !ok {
+				return nil
+			}
+			cmd, path, fs = &c, append(path, w), newFlagSet(c)
+		default:
+			pos = append(pos, w)
+		}
+	}
+
+	if len(words) > 0 {
+		if last := words[len(words)-1]; strings.HasPrefix(last, "-") && (last == "--format" || fs != nil && takesValue(fs, last)) {
+			return flagValues(strings.TrimLeft(last, "-"))
+		}
+	}
+	if strings.HasPrefix(cur, "-") {
+		return flagCandidates(cmd, fs)
+	}
+	if cmd == nil {
+		return cmdCandidates(commands)
+	}
+	if len(cmd.subs) > 0 {
+		return cmdCandidates(cmd.subs)
+	}
+	switch strings.Join(path, " ") {
+	case "help":
+		if len(pos) == 0 {
+			return cmdCandidates(commands)
+		}
+		if c, ok := findCmd(commands, pos[0]); ok && len(pos) == 1 {
+			return cmdCandidates(c.subs)
+		}
+	case "use", "cur", "list", "info", "env", "exec", "shell", "local", "outdated", "upgrade":
+		return versionCandidates(pos, installedJdks())
+	case "inst", "ls-remote":
+		return versionCandidates(pos, catalogJdks())
+	case "alias set":
+		if len(pos) > 0 {
+			return versionCandidates(pos[1:], append(catalogJdks(), installedJdks()...))
+		}
+	case "alias rm":
+		if len(pos) == 0 {
+			return aliasCandidates()
+		}
+	case "config get", "config unset":
+		if len(pos) == 0 {
+			return settingCandidates()
+		}
+	case "config set":
+		if len(pos) == 0 {
+			return settingCandidates()
+		}
+		if s, ok := findSetting(pos[0]); ok && len(pos) == 1 {
+			switch s.kind {
+			case "bool":
+				return []string{"true", "false"}
+			case "enum":
+				return s.enum
+			}
+		}
+	case "init":
+		if len(pos) == 0 {
+			return supportShell
+		}
+	case "completion":
+		if len(pos) == 0 {
+			return supportCompletion
+		}
+	}
+	return nil
+}
+
+// takesValue 参数是否需要值，--name=value 的写法已经带上了值
+func takesValue(fs *flag.FlagSet, arg string) bool {
+	if strings.Contains(arg, "=") {
+		return false
+	}
+	f := fs.Lookup(strings.TrimLeft(arg, "-"))
+	if f == nil {
+		return false
+	}
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return !ok || !b.IsBoolFlag()
+}
+
+func flagValues(name string) []string {
+	switch name {
+	case "vendor":
+		return selectVendor
+	case "arch":
+		return supportArch
+	case "shell":
+		return supportShell
+	case "format":
+		return []string{"text", "json"}
+	}
+	return nil
+}
+
+func flagCandidates(cmd *CmdInfo, fs *flag.FlagSet) []string {
+	var cs []string
+	if fs != nil {
+		fs.VisitAll(func(f *flag.Flag) {
+			_, u := flag.UnquoteUsage(f)
+			cs = append(cs, "--"+f.Name+"\t"+u)
+		})
+	}
+	if cmd == nil || cmd.json {
+		cs = append(cs, "--json\tprint the result as json", "--format\ttext|json")
+	}
+	return append(cs, "--help\tshow help")
+}
+
+func cmdCandidates(cmds []CmdInfo) []string {
+	var cs []string
+	for _, c := range cmds {
+		if !c.hidden {
+			cs = append(cs, c.cmd+"\t"+strings.Split(c.desc, "\n")[0])
+		}
+	}
+	return cs
+}
+
+// versionCandidates 第一个位置参数补全 keys 中的主版本、别名和约束，第二个补全该版本可用的厂商
+func versionCandidates(pos []string, keys []string) []string {
+	switch len(pos) {
+	case 0:
+		seen := map[string]bool{}
+		var vs []string
+		for _, k := range keys {
+			v := strings.Split(k, "_")[1]
+			if !seen[v] {
+				seen[v] = true
+				vs = append(vs, v)
+			}
+		}
+		sort.Slice(vs, func(i, j int) bool { return versionNum("_"+vs[i]) > versionNum("_"+vs[j]) })
+		return append(append(vs, constraints...), aliasCandidates()...)
+	case 1:
+		var vs []string
+		for _, k := range keys {
+			ns := strings.Split(k, "_")
+			if (ns[1] == pos[0] || contains(constraints, pos[0])) && !contains(vs, ns[0]) {
+				vs = append(vs, ns[0])
+			}
+		}
+		sort.Strings(vs)
+		return vs
+	}
+	return nil
+}
+
+func aliasCandidates() []string {
+	var cs []string
+	for name, t := range config.Aliases {
+		cs = append(cs, name+"\talias of "+t)
+	}
+	sort.Strings(cs)
+	return cs
+}
+
+func settingCandidates() []string {
+	var cs []string
+	for _, s := range settings {
+		cs = append(cs, s.key+"\t"+s.desc)
+	}
+	return cs
+}
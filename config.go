@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// configVersion 当前配置文件的结构版本，结构变化时递增并在 migrateConfig 中升级
+const configVersion = 1
+
+// Config 持久化在 config.json 中的jvm配置
+type Config struct {
+	Version int    `json:"version"`
+	Enabled bool   `json:"enabled"`
+	Active  string `json:"active,omitempty"`
+	System  bool   `json:"system,omitempty"`
+	// Completion jvm on --completion 时在shell配置块中加载补全
+	Completion bool `json:"completion,omitempty"`
+	// Settings 通过 jvm config 修改的配置项，见 settings
+	Settings map[string]string `json:"settings,omitempty"`
+	// Aliases 通过 jvm alias 设置的别名，值为 <version> [vendor] 或 latest/lts-latest 约束
+	Aliases map[string]string `json:"aliases,omitempty"`
+}
+
+var config = Config{Version: configVersion}
+
+// configRaw 加载时的文件内容，没有变化时不重写
+var configRaw []byte
+
+// configErr 加载失败时不再写回，避免覆盖无法识别的配置
+var configErr error
+
+func configFile() string {
+	return filepath.Join(workPath, "config.json")
+}
+
+func legacyConfigFile() string {
+	return filepath.Join(workPath, "jvm.cfg")
+}
+
+// loadConfig 读取 config.json，不存在时从旧的 jvm.cfg 迁移
+func loadConfig() error {
+	config = Config{Version: configVersion}
+	configRaw = nil
+	configErr = readConfig()
+	return configErr
+}
+
+func readConfig() error {
+	data, err := os.ReadFile(configFile())
+	if os.IsNotExist(err) {
+		return migrateLegacyConfig()
+	}
+	if err != nil {
+		return err
+	}
+	configRaw = data
+	if err = json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("%s is broken:%s", configFile(), err)
+	}
+	return migrateConfig()
+}
+
+// migrateConfig 把旧版本结构的配置升级到 configVersion
+func migrateConfig() error {
+	if config.Version > configVersion {
+		return fmt.Errorf("%s was written by a newer jvm(version %d), please upgrade jvm", configFile(), config.Version)
+	}
+	config.Version = configVersion
+	return nil
+}
+
+// migrateLegacyConfig 读取 key=value 格式的 jvm.cfg，忽略无法解析的行
+func migrateLegacyConfig() error {
+	file, err := os.Open(legacyConfigFile())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	r := bufio.NewScanner(file)
+	for r.Scan() {
+		kv := strings.SplitN(strings.TrimSpace(r.Text()), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "enabled":
+			config.Enabled, _ = strconv.ParseBool(kv[1])
+		case "active":
+			config.Active = kv[1]
+		case "system":
+			config.System, _ = strconv.ParseBool(kv[1])
+		}
+	}
+	if err = saveConfig(); err != nil {
+		return err
+	}
+	file.Close()
+	// 其他进程可能同时完成了迁移
+	if err = os.Rename(legacyConfigFile(), legacyConfigFile()+".bak"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// saveConfig 配置有变化时先写临时文件再重命名，避免写到一半的配置
+func saveConfig() error {
+	if configErr != nil {
+		return nil
+	}
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if bytes.Equal(data, configRaw) {
+		return nil
+	}
+	tmp, err := os.CreateTemp(workPath, "config-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), configFile()); err != nil {
+		return err
+	}
+	configRaw = data
+	return nil
+}
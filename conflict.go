@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"github.com/dtdyq/jvm/local"
+	"github.com/fatih/color"
+	"golang.org/x/term"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// javaSetting 配置文件中一条与jvm冲突的java环境设置
+type javaSetting struct {
+	file string
+	line int
+	text string
+	home string
+}
+
+var javaHomeRe = regexp.MustCompile(`^(?:export\s+|setenv\s+|set\s+(?:-[a-zA-Z]+\s+)*)?JAVA_HOME(?:\s*=\s*|\s+)(.*)$`)
+var javaPathRe = regexp.MustCompile(`PATH.*?([^\s:'"=]*(?:jdk|java|jre)[^\s:'"]*/bin)\b`)
+
+// envFiles 返回可能设置java环境的配置文件
+func envFiles() []string {
+	hd, _ := os.UserHomeDir()
+	var fs []string
+	for _, n := range []string{".bashrc", ".bash_profile", ".bash_login", ".profile", ".zshrc", ".zprofile", ".zshenv", ".config/fish/config.fish"} {
+		fs = append(fs, filepath.Join(hd, n))
+	}
+	if zd := os.Getenv("ZDOTDIR"); zd != "" {
+		fs = append(fs, filepath.Join(zd, ".zshrc"), filepath.Join(zd, ".zprofile"), filepath.Join(zd, ".zshenv"))
+	}
+	fs = append(fs, "/etc/environment", "/etc/profile")
+	for _, g := range []string{"/etc/profile.d/*", filepath.Join(hd, ".config", "environment.d", "*.conf"), filepath.Join(hd, ".config", "fish", "conf.d", "*.fish")} {
+		ms, _ := filepath.Glob(g)
+		fs = append(fs, ms...)
+	}
+	return fs
+}
+
+// scanJavaSettings 找出jvm配置块之外设置 JAVA_HOME 或把jdk加入PATH的行
+func scanJavaSettings() []javaSetting {
+	var found []javaSetting
+	seen := map[string]bool{}
+	for _, f := range envFiles() {
+		if seen[f] || strings.Contains(filepath.Base(f), ".jvm-backup-") {
+			continue
+		}
+		seen[f] = true
+		file, err := os.Open(f)
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(file)
+		in := false
+		for n := 1; scanner.Scan(); n++ {
+			t := strings.TrimSpace(scanner.Text())
+			switch {
+			case t == local.BlockBegin:
+				in = true
+				continue
+			case t == local.BlockEnd:
+				in = false
+				continue
+			case in || t == "" || strings.HasPrefix(t, "#"):
+				continue
+			}
+			if m := javaHomeRe.FindStringSubmatch(t); m != nil {
+				found = append(found, javaSetting{file: f, line: n, text: t, home: settingHome(m[1])})
+			} else if m := javaPathRe.FindStringSubmatch(t); m != nil && !strings.Contains(m[1], "JAVA_HOME") {
+				found = append(found, javaSetting{file: f, line: n, text: t, home: settingHome(filepath.Dir(m[1]))})
+			}
+		}
+		file.Close()
+	}
+	return found
+}
+
+// settingHome 从配置的值中解析出jdk目录，不是jvm管理且包含 bin/java 时才返回
+func settingHome(v string) string {
+	v = strings.Trim(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(v), ";")), `"'`)
+	if strings.ContainsAny(v, "`(") {
+		return ""
+	}
+	v = os.ExpandEnv(v)
+	if !filepath.IsAbs(v) || strings.HasPrefix(v, workPath) || strings.HasPrefix(v, dataPath) || strings.HasPrefix(v, jdkPath) || v == local.JdkHomeLinkPath {
+		return ""
+	}
+	if !pathExist(filepath.Join(v, "bin", "java")) && !pathExist(filepath.Join(v, "bin", "java.exe")) {
+		return ""
+	}
+	return filepath.Clean(v)
+}
+
+var stdin = bufio.NewReader(os.Stdin)
+
+// assumeYes 为 true 时所有询问都按同意处理
+var assumeYes = false
+
+// confirm 在终端中询问用户，非交互环境默认为否
+func confirm(format string, a ...interface{}) bool {
+	if assumeYes {
+		return true
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return false
+	}
+	color.New(color.FgYellow).Printf(format+" [y/N] ", a...)
+	ans, _ := stdin.ReadString('\n')
+	ans = strings.ToLower(strings.TrimSpace(ans))
+	return ans == "y" || ans == "yes"
+}
+
+// commentOut 备份后注释掉文件中的冲突行
+func commentOut(file string, lines map[int]bool) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	bak, err := local.BackupFile(file)
+	if err != nil {
+		return err
+	}
+	ls := strings.Split(string(data), "\n")
+	for i := range ls {
+		if lines[i+1] {
+			ls[i] = "# disabled by jvm: " + ls[i]
+		}
+	}
+	if err = os.WriteFile(file, []byte(strings.Join(ls, "\n")), info.Mode().Perm()); err != nil {
+		return err
+	}
+	color.White("commented out %d line(s) in %s, backup at %s", len(lines), file, bak)
+	return nil
+}
+
+// adoptJdk 把已有的jdk以 local 厂商链接进jvm，不复制文件
+func adoptJdk(home string) {
+	version := majorVersion(readRelease(home)["JAVA_VERSION"])
+	if !contains(supportVersion, version) {
+		color.Yellow("skip %s: un support version:%s", home, version)
+		return
+	}
+	key := downloadKeyBy(version, adoptedVendor)
+	if pathExist(filepath.Join(jdkPath, key)) {
+		color.Yellow("skip %s: %s already exists", home, key)
+		return
+	}
+	if !confirm("adopt %s as [jvm use %s local]?", home, version) {
+		return
+	}
+	if err := os.Symlink(home, filepath.Join(jdkPath, key)); err != nil {
+		color.Red("adopt %s fail:%s", home, err)
+		return
+	}
+	if err := writeManifest(key, "", ""); err != nil {
+		color.Yellow("write manifest of %s fail:%s", key, err)
+	}
+	color.Green("adopted %s, use [jvm use %s local] to active", home, version)
+	installedChanged()
+}
+
+// migrateJavaSettings 报告会覆盖jvm的旧java环境设置，询问是否注释掉并接管其中的jdk
+func migrateJavaSettings() {
+	found := scanJavaSettings()
+	if len(found) == 0 {
+		return
+	}
+	color.Yellow("found java settings that may override jvm:")
+	byFile := map[string]map[int]bool{}
+	var files []string
+	var homes []string
+	for _, s := range found {
+		color.Yellow("  %s:%d: %s", s.file, s.line, s.text)
+		if byFile[s.file] == nil {
+			byFile[s.file] = map[int]bool{}
+			files = append(files, s.file)
+		}
+		byFile[s.file][s.line] = true
+		if s.home != "" && !contains(homes, s.home) {
+			homes = append(homes, s.home)
+		}
+	}
+	if confirm("comment them out (a backup of each file is kept)?") {
+		for _, f := range files {
+			if err := commentOut(f, byFile[f]); err != nil {
+				color.Red("%s", err)
+			}
+		}
+	} else {
+		color.Yellow("left unchanged, they may still override JAVA_HOME set by jvm")
+	}
+	for _, h := range homes {
+		adoptJdk(h)
+	}
+}
+
+// majorVersion 把 1.8.0_392、17.0.10 这样的版本号转换为主版本号
+func majorVersion(v string) string {
+	fs := strings.FieldsFunc(strings.TrimPrefix(v, "1."), func(r rune) bool {
+		return r == '.' || r == '_' || r == '+' || r == '-'
+	})
+	if len(fs) == 0 {
+		return ""
+	}
+	return fs[0]
+}
+
+// readRelease 读取jdk目录下 release 文件中的键值对
+func readRelease(home string) map[string]string {
+	ret := map[string]string{}
+	file, err := os.Open(filepath.Join(home, "release"))
+	if err != nil {
+		return ret
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		kv := strings.SplitN(scanner.Text(), "=", 2)
+		if len(kv) == 2 {
+			ret[strings.TrimSpace(kv[0])] = strings.Trim(strings.TrimSpace(kv[1]), `"`)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/dtdyq/jvm/local"
+	"github.com/fatih/color"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// doctorCheck jvm doctor 的一项检查结果
+type doctorCheck struct {
+	Name string `json:"name"`
+	// Status ok|warn|fail|skip
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+	Fix    string `json:"fix,omitempty"`
+}
+
+func checkResult(name, status, fix, format string, a ...interface{}) doctorCheck {
+	return doctorCheck{Name: name, Status: status, Detail: fmt.Sprintf(format, a...), Fix: fix}
+}
+
+// samePath 两个路径跟随链接后是否为同一个目录
+func samePath(a, b string) bool {
+	if ra, err := filepath.EvalSymlinks(a); err == nil {
+		a = ra
+	}
+	if rb, err := filepath.EvalSymlinks(b); err == nil {
+		b = rb
+	}
+	return filepath.Clean(a) == filepath.Clean(b)
+}
+
+// jdkKeyOf 返回 dir 对应的已安装jdk，不是jvm管理的目录时返回空
+func jdkKeyOf(dir string) string {
+	for _, k := range installedJdks() {
+		if samePath(dir, filepath.Join(jdkPath, k)) {
+			return k
+		}
+	}
+	return ""
+}
+
+// selection 按 JVM_VERSION > 项目版本文件 > 全局激活 应使用的jdk及其来源
+func selection() (string, string) {
+	key, src := resolveKey()
+	return key, sourceLabel(src)
+}
+
+// javaKeyOf 返回 PATH 中找到的 java 实际运行的jdk，shim 按当前的选择解析，不是jvm管理的返回空
+func javaKeyOf(java string) string {
+	dir := filepath.Dir(java)
+	if samePath(dir, shimsPath()) {
+		key, _ := resolveKey()
+		return key
+	}
+	return jdkKeyOf(filepath.Dir(dir))
+}
+
+func activeArgs() string {
+	ns := strings.Split(config.Active, "_")
+	return ns[1] + " " + ns[0]
+}
+
+// reloadFix 让当前shell按jvm的选择重新设置环境的修复建议
+func reloadFix() string {
+	switch {
+	case runtime.GOOS == "windows":
+		return "open a new terminal"
+	case detectShell() == "fish":
+		return "run [jvm env | source], or open a new terminal"
+	}
+	return "run [eval \"$(jvm env)\"], or open a new terminal"
+}
+
+func pathHas(dir string) bool {
+	for _, p := range filepath.SplitList(os.Getenv("PATH")) {
+		if p != "" && samePath(p, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkEnabled() doctorCheck {
+	if !config.Enabled {
+		return checkResult("enabled", "fail", "jvm on", "jvm is not enabled in %s", configFile())
+	}
+	return checkResult("enabled", "ok", "", "jvm is enabled")
+}
+
+// checkProfile 配置块是否写入了shell配置，以及当前shell是否已经加载
+func checkProfile() []doctorCheck {
+	if !config.Enabled {
+		return []doctorCheck{checkResult("profile", "skip", "", "jvm is not enabled")}
+	}
+	where, ok := local.ProfileConfigured(homeLinkPath(), shimsPath(), config.System)
+	if !ok {
+		return []doctorCheck{checkResult("profile", "fail", "jvm on", "%s has no jvm setup for %s", where, shimsPath())}
+	}
+	cs := []doctorCheck{checkResult("profile", "ok", "", "%s sets up jvm", where)}
+	if pathHas(shimsPath()) {
+		return append(cs, checkResult("profile loaded", "ok", "", "%s is on PATH", shimsPath()))
+	}
+	fix := "open a new terminal, or run [source " + local.ShellQuote(detectShell(), where) + "]"
+	if runtime.GOOS == "windows" {
+		fix = "open a new terminal"
+	}
+	return append(cs, checkResult("profile loaded", "fail", fix, "this shell has not loaded the jvm setup, %s is not on PATH", shimsPath()))
+}
+
+// conflictFix 返回设置了 dir 的冲突配置行的修复建议
+func conflictFix(dir string, conflicts []javaSetting) string {
+	for _, c := range conflicts {
+		if c.home != "" && samePath(c.home, dir) {
+			return fmt.Sprintf("remove %s:%d [%s], or run [jvm on --yes] to comment it out", c.file, c.line, c.text)
+		}
+	}
+	return ""
+}
+
+// checkJavaHome 当前环境的 JAVA_HOME 是否指向应使用的jdk
+func checkJavaHome(conflicts []javaSetting) doctorCheck {
+	want, src := selection()
+	jh := os.Getenv("JAVA_HOME")
+	switch {
+	case want == "":
+		return checkResult("JAVA_HOME", "skip", "", "no jdk selected")
+	case jh == "":
+		return checkResult("JAVA_HOME", "warn", "open a new terminal after [jvm on]", "JAVA_HOME is not set in this shell")
+	case samePath(jh, filepath.Join(jdkPath, want)):
+		return checkResult("JAVA_HOME", "ok", "", "%s is %s from %s", jh, jdkLabel(want), src)
+	}
+	if k := jdkKeyOf(jh); k != "" {
+		return checkResult("JAVA_HOME", "warn", reloadFix(), "%s is %s, expected %s from %s", jh, jdkLabel(k), jdkLabel(want), src)
+	}
+	fix := conflictFix(jh, conflicts)
+	if fix == "" {
+		fix = "unset JAVA_HOME where it is set, then open a new terminal"
+	}
+	return checkResult("JAVA_HOME", "fail", fix, "%s is not managed by jvm, expected %s from %s", jh, jdkLabel(want), src)
+}
+
+// checkHomeLink 当前jdk的链接是否存在并指向激活的jdk
+func checkHomeLink() doctorCheck {
+	link := homeLinkPath()
+	if config.Active == "" {
+		return checkResult("home link", "skip", "", "no jdk activated")
+	}
+	fix := "jvm use " + activeArgs()
+	if _, err := os.Lstat(link); err != nil {
+		return checkResult("home link", "fail", fix, "%s does not exist", link)
+	}
+	target, err := filepath.EvalSymlinks(link)
+	if err != nil {
+		t, _ := os.Readlink(link)
+		return checkResult("home link", "fail", "jvm inst "+activeArgs()+", then "+fix, "%s points to %s which does not exist", link, t)
+	}
+	if !samePath(target, filepath.Join(jdkPath, config.Active)) {
+		return checkResult("home link", "warn", fix, "%s points to %s, but the active jdk is %s", link, target, jdkLabel(config.Active))
+	}
+	return checkResult("home link", "ok", "", "%s -> %s", link, target)
+}
+
+// checkJavaOnPath 找出PATH中第一个 java 以及它为什么在最前面
+func checkJavaOnPath(conflicts []javaSetting) doctorCheck {
+	p, err := exec.LookPath("java")
+	if err != nil {
+		return checkResult("java on PATH", "fail", "jvm on, then open a new terminal", "no java found on PATH")
+	}
+	dir := filepath.Dir(p)
+	want, src := selection()
+	if samePath(dir, shimsPath()) {
+		if want == "" {
+			return checkResult("java on PATH", "fail", "jvm use <version>", "%s is the jvm shim, but no jdk is selected", p)
+		}
+		if !pathExist(filepath.Join(jdkPath, want)) {
+			ns := strings.Split(want, "_")
+			return checkResult("java on PATH", "fail", "jvm inst "+ns[1]+" "+ns[0], "%s is the jvm shim, but %s from %s is not installed", p, want, src)
+		}
+		return checkResult("java on PATH", "ok", "", "%s is the jvm shim, runs %s from %s", p, jdkLabel(want), src)
+	}
+	if k := jdkKeyOf(filepath.Dir(dir)); k != "" {
+		if want != "" && k != want {
+			return checkResult("java on PATH", "warn", reloadFix(), "%s is %s put first by [jvm env] or [jvm shell], expected %s from %s", p, jdkLabel(k), jdkLabel(want), src)
+		}
+		return checkResult("java on PATH", "ok", "", "%s is %s put first by [jvm env] or [jvm shell]", p, jdkLabel(k))
+	}
+	if samePath(dir, filepath.Join(homeLinkPath(), "bin")) {
+		return checkResult("java on PATH", "ok", "", "%s is the active jdk %s", p, jdkLabel(config.Active))
+	}
+	why := fmt.Sprintf("%s is not on PATH", shimsPath())
+	if pathHas(shimsPath()) {
+		why = fmt.Sprintf("%s comes before %s in PATH", dir, shimsPath())
+	}
+	fix := conflictFix(filepath.Dir(dir), conflicts)
+	if fix == "" {
+		fix = "put " + shimsPath() + " first in PATH, open a new terminal after [jvm on]"
+	}
+	return checkResult("java on PATH", "fail", fix, "%s is not managed by jvm, %s", p, why)
+}
+
+func checkConflicts(conflicts []javaSetting) []doctorCheck {
+	if len(conflicts) == 0 {
+		return []doctorCheck{checkResult("conflicts", "ok", "", "no java settings outside the jvm block")}
+	}
+	var cs []doctorCheck
+	for _, c := range conflicts {
+		cs = append(cs, checkResult("conflicts", "warn", "jvm on --yes comments it out", "%s:%d %s", c.file, c.line, c.text))
+	}
+	return cs
+}
+
+// checkSystemLink 系统目录下的链接是否可写，只有 --system 模式需要
+func checkSystemLink() doctorCheck {
+	err := local.CheckSystemWritable()
+	switch {
+	case err == nil:
+		return checkResult("system link", "ok", "", "%s is writable", local.JdkHomeLinkPath)
+	case config.System:
+		return checkResult("system link", "fail", "rerun jvm as root or administrator, or [jvm on] for user mode", "%s", err)
+	}
+	return checkResult("system link", "skip", "", "%s is not writable, only needed by [jvm on --system]", local.JdkHomeLinkPath)
+}
+
+// checkInstall 激活的jdk是否可以运行
+func checkInstall() doctorCheck {
+	if config.Active == "" {
+		return checkResult("active jdk", "warn", "jvm use <version>", "no jdk activated")
+	}
+	home := filepath.Join(jdkPath, config.Active)
+	reinstall := "jvm inst " + activeArgs() + " --force"
+	if !pathExist(home) {
+		return checkResult("active jdk", "fail", "jvm inst "+activeArgs(), "%s is active but not installed", config.Active)
+	}
+	java := filepath.Join(home, "bin", "java")
+	if runtime.GOOS == "windows" {
+		java += ".exe"
+	}
+	if !pathExist(java) {
+		return checkResult("active jdk", "fail", reinstall, "%s is missing, the install is broken", java)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, java, "-version").CombinedOutput()
+	if err != nil {
+		return checkResult("active jdk", "fail", reinstall, "%s -version fail:%s", java, err)
+	}
+	return checkResult("active jdk", "ok", "", "%s: %s", jdkLabel(config.Active), strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0]))
+}
+
+// checkSize 激活的jdk的总大小是否与安装时记录的相同，只能发现增删文件或改变了大小的修改
+func checkSize() doctorCheck {
+	home := filepath.Join(jdkPath, config.Active)
+	m, ok := readManifest(config.Active)
+	if config.Active == "" || !pathExist(home) || !ok {
+		return checkResult("install size", "skip", "", "no install manifest of the active jdk")
+	}
+	size := dirSize(home)
+	if size == m.Size {
+		return checkResult("install size", "ok", "", "%s is %s as when installed", home, formatSize(size))
+	}
+	now, was := formatSize(size), formatSize(m.Size)
+	if now == was {
+		now, was = fmt.Sprintf("%d bytes", size), fmt.Sprintf("%d bytes", m.Size)
+	}
+	return checkResult("install size", "warn", "jvm inst "+activeArgs()+" --force", "%s is %s, %s when installed, files were added, removed or changed", home, now, was)
+}
+
+// doctorJdk 检查jvm的环境是否生效，给出修复建议
+func doctorJdk(subs []string) {
+	conflicts := scanJavaSettings()
+	cs := []doctorCheck{checkEnabled()}
+	cs = append(cs, checkProfile()...)
+	cs = append(cs, checkJavaHome(conflicts), checkHomeLink(), checkJavaOnPath(conflicts))
+	cs = append(cs, checkConflicts(conflicts)...)
+	cs = append(cs, checkSystemLink(), checkInstall(), checkSize())
+
+	fails := 0
+	for _, c := range cs {
+		if c.Status == "fail" {
+			fails++
+		}
+	}
+	if jsonOutput() {
+		printJSON("doctor", map[string]interface{}{"checks": cs})
+	} else {
+		for _, c := range cs {
+			status := color.New(color.FgGreen)
+			switch c.Status {
+			case "warn":
+				status = color.New(color.FgYellow)
+			case "fail":
+				status = color.New(color.FgRed)
+			case "skip":
+				status = color.New(color.FgWhite)
+			}
+			status.Printf("  %-5s", c.Status)
+			color.New(color.FgCyan).Printf(" %-15s", c.Name)
+			color.White(" %s", c.Detail)
+			if c.Fix != "" {
+				color.White("  %-5s %-15s fix: %s", "", "", c.Fix)
+			}
+		}
+	}
+	if fails > 0 {
+		failf("%d problem(s) found", fails)
+	}
+}
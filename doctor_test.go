@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestReloadFix(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("windows always suggests a new terminal")
+	}
+	tests := []struct{ shell, want string }{
+		{"/bin/bash", `run [eval "$(jvm env)"], or open a new terminal`},
+		{"/bin/zsh", `run [eval "$(jvm env)"], or open a new terminal`},
+		{"/bin/sh", `run [eval "$(jvm env)"], or open a new terminal`},
+		{"/usr/bin/fish", "run [jvm env | source], or open a new terminal"},
+	}
+	for _, tt := range tests {
+		t.Run(filepath.Base(tt.shell), func(t *testing.T) {
+			t.Setenv("SHELL", tt.shell)
+			if got := reloadFix(); got != tt.want {
+				t.Errorf("reloadFix() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckJavaHomeMismatch(t *testing.T) {
+	defer func(p, a string) { jdkPath, config.Active = p, a }(jdkPath, config.Active)
+	dir := t.TempDir()
+	jdkPath = dir
+	for _, k := range []string{"liberica_17_linux_x64", "liberica_21_linux_x64"} {
+		if err := os.MkdirAll(filepath.Join(dir, k, "bin"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	config.Active = "liberica_17_linux_x64"
+	t.Setenv("JVM_VERSION", "")
+	t.Setenv("SHELL", "/bin/bash")
+	t.Setenv("JAVA_HOME", filepath.Join(dir, "liberica_21_linux_x64"))
+
+	c := checkJavaHome(nil)
+	if c.Status != "warn" || c.Fix != reloadFix() || !strings.Contains(c.Detail, "expected") {
+		t.Errorf("checkJavaHome() = %+v", c)
+	}
+}
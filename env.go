@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"github.com/dtdyq/jvm/local"
+	"github.com/fatih/color"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var supportShell = []string{"bash", "zsh", "fish", "sh"}
+
+// detectShell 推断用户使用的shell，无法识别时退回 POSIX sh
+func detectShell() string {
+	sh := local.LoginShell()
+	if contains(supportShell, sh) {
+		return sh
+	}
+	return "sh"
+}
+
+// popFlag 从参数中取出 name 对应的值(--name value 或 --name=value)，返回值和剩余参数
+func popFlag(subs []string, name string) (string, []string) {
+	var val string
+	var rest []string
+	for i := 0; i < len(subs); i++ {
+		switch {
+		case subs[i] == name && i+1 < len(subs):
+			val = subs[i+1]
+			i++
+		case strings.HasPrefix(subs[i], name+"="):
+			val = strings.TrimPrefix(subs[i], name+"=")
+		default:
+			rest = append(rest, subs[i])
+		}
+	}
+	return val, rest
+}
+
+// sessionPath 把jdk的bin放到PATH最前面，并去掉PATH中其他由jvm管理的jdk，home 为空时只去掉
+func sessionPath(home string) []string {
+	var ps []string
+	bin := ""
+	if home != "" {
+		bin = filepath.Join(home, "bin")
+		ps = append(ps, bin)
+	}
+	for _, p := range filepath.SplitList(os.Getenv("PATH")) {
+		if p == "" || p == bin || strings.HasPrefix(p, jdkPath+string(filepath.Separator)) {
+			continue
+		}
+		ps = append(ps, p)
+	}
+	return ps
+}
+
+// envScript 生成在当前会话中激活 home 指向的jdk的shell代码，pin 不为空时同时设置 JVM_VERSION 固定当前shell的选择
+func envScript(shell, home, pin string) string {
+	ps := sessionPath(home)
+	var b strings.Builder
+	if shell == "fish" {
+		if pin != "" {
+			b.WriteString(fmt.Sprintf("set -gx JVM_VERSION %s;\n", local.ShellQuote(shell, pin)))
+		}
+		b.WriteString(fmt.Sprintf("set -gx JAVA_HOME %s;\n", local.ShellQuote(shell, home)))
+		b.WriteString("set -gx PATH")
+		for _, p := range ps {
+			b.WriteString(" " + local.ShellQuote(shell, p))
+		}
+		b.WriteString(";\n")
+		return b.String()
+	}
+	if pin != "" {
+		b.WriteString(fmt.Sprintf("export JVM_VERSION=%s;\n", local.ShellQuote(shell, pin)))
+	}
+	b.WriteString(fmt.Sprintf("export JAVA_HOME=%s;\n", local.ShellQuote(shell, home)))
+	b.WriteString(fmt.Sprintf("export PATH=%s;\n", local.ShellQuote(shell, strings.Join(ps, string(filepath.ListSeparator)))))
+	return b.String()
+}
+
+// unsetScript 撤销 envScript 在当前会话中的设置，用于离开项目目录且没有全局激活的jdk时
+func unsetScript(shell string) string {
+	ps := sessionPath("")
+	if shell == "fish" {
+		var b strings.Builder
+		b.WriteString("set -e JAVA_HOME;\nset -gx PATH")
+		for _, p := range ps {
+			b.WriteString(" " + local.ShellQuote(shell, p))
+		}
+		b.WriteString(";\n")
+		return b.String()
+	}
+	return fmt.Sprintf("unset JAVA_HOME;\nexport PATH=%s;\n", local.ShellQuote(shell, strings.Join(ps, string(filepath.ListSeparator))))
+}
+
+const posixInit = `jvm() {
+  if [ "$1" = "use" ]; then
+    shift
+    eval "$(command jvm env --shell %s "$@")"
+  else
+    command jvm "$@"
+  fi
+}
+`
+
+// 进入目录时按项目版本文件切换jdk
+const bashHook = `_jvm_hook() {
+  if [ "$PWD" != "${_JVM_PWD-}" ]; then
+    _JVM_PWD="$PWD"
+    eval "$(command jvm env --hook --shell bash)"
+  fi
+}
+case ";${PROMPT_COMMAND-};" in
+  *";_jvm_hook;"*) ;;
+  *) PROMPT_COMMAND="_jvm_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}" ;;
+esac
+`
+
+const zshHook = `_jvm_hook() {
+  eval "$(command jvm env --hook --shell zsh)"
+}
+autoload -Uz add-zsh-hook
+add-zsh-hook chpwd _jvm_hook
+_jvm_hook
+`
+
+const fishInit = `function jvm
+  if test "$argv[1]" = use
+    command jvm env --shell fish $argv[2..-1] | source
+  else
+    command jvm $argv
+  end
+end
+
+function _jvm_hook --on-variable PWD
+  command jvm env --hook --shell fish | source
+end
+_jvm_hook
+`
+
+// envJdk 输出只对当前shell生效的 JAVA_HOME/PATH 设置，提示信息走stderr以免被eval
+func envJdk(subs []string) {
+	color.Output = color.Error
+	shell := flagShell
+	if flagHook {
+		// 由 jvm init 中的目录切换钩子调用
+		if !getBoolSetting("auto-switch") {
+			return
+		}
+		subs = subs[:0]
+	}
+	if shell == "" {
+		shell = detectShell()
+	}
+	if !contains(supportShell, shell) {
+		usagef("un support shell:%s, one of %s", shell, strings.Join(supportShell, "|"))
+		return
+	}
+	// 不带版本时按项目版本文件或全局设置决定，已经生效时不输出
+	if len(subs) == 0 {
+		home, _ := resolveHome()
+		jh := os.Getenv("JAVA_HOME")
+		switch {
+		case home != "" && home != jh:
+			fmt.Print(envScript(shell, home, ""))
+		case home == "" && strings.HasPrefix(jh, jdkPath+string(filepath.Separator)):
+			// 之前由项目版本文件设置的jdk，没有可以恢复的全局jdk时撤销
+			fmt.Print(unsetScript(shell))
+		}
+		return
+	}
+	key, ok := parseVersionVendor(subs)
+	if !ok {
+		return
+	}
+	home := filepath.Join(jdkPath, key)
+	if !pathExist(home) {
+		failCode(exitNotInstalled, "%s not installed, try [jvm inst %s] first", key, strings.Join(subs, " "))
+		return
+	}
+	ns := strings.Split(key, "_")
+	fmt.Print(envScript(shell, home, ns[1]+" "+ns[0]))
+}
+
+// initShell 输出shell集成代码，让 jvm use 改为只修改当前shell的环境，并在切换目录时按项目版本文件切换jdk
+func initShell(subs []string) {
+	shell := detectShell()
+	if len(subs) > 0 {
+		shell = subs[0]
+	}
+	switch shell {
+	case "fish":
+		fmt.Print(fishInit)
+	case "bash":
+		fmt.Printf(posixInit, shell)
+		fmt.Print(bashHook)
+	case "zsh":
+		fmt.Printf(posixInit, shell)
+		fmt.Print(zshHook)
+	case "sh":
+		fmt.Printf(posixInit, shell)
+	default:
+		usagef("un support shell:%s, one of %s", shell, strings.Join(supportShell, "|"))
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"github.com/fatih/color"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+)
+
+// jdkEnv 把当前进程的环境切换到 home 指向的jdk，子进程直接继承
+func jdkEnv(home, key string) {
+	ns := strings.Split(key, "_")
+	os.Setenv("JAVA_HOME", home)
+	os.Setenv("JDK_HOME", home)
+	os.Setenv("JVM_VERSION", ns[1]+" "+ns[0])
+	os.Setenv("PATH", strings.Join(sessionPath(home), string(filepath.ListSeparator)))
+}
+
+// runChild 运行子进程并转发输入输出和信号，返回子进程的退出码
+func runChild(name string, args []string) int {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		color.Red("%s", err)
+		return 127
+	}
+	// Ctrl-C 由终端发给整个前台进程组，子进程已经收到，父进程忽略它等子进程退出；
+	// 在 Start 之后忽略，避免子进程继承忽略的设置
+	signal.Ignore(os.Interrupt)
+	defer signal.Reset(os.Interrupt)
+	// SIGTERM 只发给了jvm，需要转发给子进程
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+	go func() {
+		for sig := range sigs {
+			cmd.Process.Signal(sig)
+		}
+	}()
+	if err := cmd.Wait(); err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			return ee.ExitCode()
+		}
+		color.Red("%s", err)
+		return 1
+	}
+	return 0
+}
+
+// execJdk 用指定的jdk运行一次命令，不修改全局激活的jdk，以命令的退出码退出
+func execJdk(subs []string) {
+	idx := -1
+	for i, s := range subs {
+		if s == "--" {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 || idx == len(subs)-1 {
+		usagef("usage: jvm exec <version> [vendor] [--install] -- <command> [args]")
+		return
+	}
+	rest, command := subs[:idx], subs[idx+1:]
+	if len(rest) == 0 {
+		usagef("version required,use [jvm help exec] for detail")
+		return
+	}
+	key, ok := parseVersionVendor(rest)
+	if !ok {
+		return
+	}
+	home := filepath.Join(jdkPath, key)
+	if !pathExist(home) {
+		if !flagInstall {
+			failCode(exitNotInstalled, "%s not installed, try [jvm inst %s] first or add --install", key, strings.Join(rest, " "))
+			return
+		}
+		if err := installJdk(key); err != nil {
+			failErr(err)
+			return
+		}
+	}
+	jdkEnv(home, key)
+	code := runChild(command[0], command[1:])
+	// os.Exit 不会执行 main 中的 defer
+	exit()
+	os.Exit(code)
+}
+
+// shellJdk 启动一个激活了指定jdk的子shell，退出后回到原来的环境
+func shellJdk(subs []string) {
+	if len(subs) == 0 {
+		usagef("version required,use [jvm help shell] for detail")
+		return
+	}
+	key, ok := parseVersionVendor(subs)
+	if !ok {
+		return
+	}
+	home := filepath.Join(jdkPath, key)
+	if !pathExist(home) {
+		failCode(exitNotInstalled, "%s not installed, try [jvm inst %s] first", key, strings.Join(subs, " "))
+		return
+	}
+	sh := os.Getenv("SHELL")
+	if runtime.GOOS == "windows" {
+		sh = os.Getenv("COMSPEC")
+	}
+	if sh == "" {
+		sh = "/bin/sh"
+	}
+	ns := strings.Split(key, "_")
+	if act := os.Getenv("JVM_ACTIVE"); act != "" {
+		color.Yellow("already in a jvm shell of %s, nesting a new one", act)
+	}
+	jdkEnv(home, key)
+	os.Setenv("JVM_ACTIVE", ns[1]+"-"+ns[0])
+	color.Green("entering %s with %s %s, exit to return", sh, ns[0], ns[1])
+	code := runChild(sh, nil)
+	color.Green("left jvm shell of %s %s", ns[0], ns[1])
+	exit()
+	os.Exit(code)
+}
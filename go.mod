@@ -6,6 +6,7 @@ require (
 	github.com/fatih/color v1.16.0
 	github.com/schollz/progressbar/v3 v3.14.1
 	golang.org/x/sys v0.16.0
+	golang.org/x/term v0.16.0
 )
 
 require (
@@ -14,5 +15,4 @@ require (
 	github.com/mitchellh/colorstring v0.0.0-20190213212951-d06e56a500db // indirect
 	github.com/rivo/uniseg v0.4.4 // indirect
 	github.com/stretchr/testify v1.8.4 // indirect
-	golang.org/x/term v0.16.0 // indirect
 )
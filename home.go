@@ -0,0 +1,120 @@
+package main
+
+import (
+	"github.com/dtdyq/jvm/local"
+	"github.com/fatih/color"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// dataPath 存放jdk、shims和当前jdk链接的目录
+var dataPath = ""
+
+// cacheRoot 下载的安装包缓存目录
+var cacheRoot = ""
+
+// movedJdkPath 本次从 ~/.jvm 迁移前的jdk目录，ide中指向它的条目仍由jvm管理
+var movedJdkPath = ""
+
+// xdgDir 返回 XDG 目录变量的值，未设置或不是绝对路径时使用默认值
+func xdgDir(env, def string) string {
+	if d := os.Getenv(env); filepath.IsAbs(d) {
+		return d
+	}
+	return def
+}
+
+// homeDirs 按 JVM_HOME > XDG(linux) > ~/.jvm 的顺序确定配置、数据和缓存目录
+func homeDirs(hd string) (string, string, string) {
+	if h := os.Getenv("JVM_HOME"); h != "" {
+		if abs, err := filepath.Abs(h); err == nil {
+			h = abs
+		}
+		return h, h, filepath.Join(h, "cache")
+	}
+	if runtime.GOOS != "linux" {
+		h := filepath.Join(hd, ".jvm")
+		return h, h, filepath.Join(h, "cache")
+	}
+	return filepath.Join(xdgDir("XDG_CONFIG_HOME", filepath.Join(hd, ".config")), "jvm"),
+		filepath.Join(xdgDir("XDG_DATA_HOME", filepath.Join(hd, ".local", "share")), "jvm"),
+		filepath.Join(xdgDir("XDG_CACHE_HOME", filepath.Join(hd, ".cache")), "jvm")
+}
+
+// legacyHome 需要迁移的旧 ~/.jvm，没有或不需要迁移时返回空
+func legacyHome(hd string) string {
+	old := filepath.Join(hd, ".jvm")
+	if old == workPath || pathExist(configFile()) || pathExist(legacyConfigFile()) {
+		return ""
+	}
+	if !pathExist(filepath.Join(old, "config.json")) && !pathExist(filepath.Join(old, "jvm.cfg")) {
+		return ""
+	}
+	return old
+}
+
+// moveHome 把旧 ~/.jvm 中的配置、jdk和缓存移到新的目录，重新生成链接、shims和shell配置
+func moveHome(old string) {
+	color.Yellow("moving %s to %s", old, workPath)
+	for _, n := range []string{"config.json", "jvm.cfg", "jvm.cfg.bak"} {
+		if pathExist(filepath.Join(old, n)) {
+			if err := os.Rename(filepath.Join(old, n), filepath.Join(workPath, n)); err != nil {
+				color.Red("move %s fail:%s", n, err)
+				return
+			}
+		}
+	}
+	if err := loadConfig(); err != nil {
+		color.Red("%s", err)
+		return
+	}
+	oldJdks := filepath.Join(old, "jdks")
+	if _, ok := config.Settings["install-dir"]; !ok && pathExist(oldJdks) {
+		newJdks := filepath.Join(dataPath, "jdks")
+		os.Remove(newJdks)
+		if err := os.Rename(oldJdks, newJdks); err != nil {
+			// 跨文件系统时不复制jdk，继续使用原来的目录
+			if config.Settings == nil {
+				config.Settings = map[string]string{}
+			}
+			config.Settings["install-dir"] = oldJdks
+			color.Yellow("keep jdks in %s(%s), move them by hand and [jvm config unset install-dir] if needed", oldJdks, err)
+		} else {
+			movedJdkPath = oldJdks
+		}
+	}
+	if pathExist(filepath.Join(old, "cache")) {
+		os.Remove(cacheRoot)
+		if err := os.Rename(filepath.Join(old, "cache"), cacheRoot); err != nil {
+			os.RemoveAll(filepath.Join(old, "cache"))
+		}
+	}
+	jdkPath = filepath.Join(dataPath, "jdks")
+	if d := getSetting("install-dir"); d != "" {
+		jdkPath = d
+	}
+	os.MkdirAll(jdkPath, os.ModePerm)
+	// 用户模式的链接和shims在旧目录下，重新生成
+	if config.Active != "" && pathExist(filepath.Join(jdkPath, config.Active)) {
+		if !config.System {
+			changeEnvSymbol(config.Active)
+		} else if err := local.CheckSystemWritable(); err == nil {
+			changeEnvSymbol(config.Active)
+		} else {
+			color.Yellow("%s still points to %s, run [jvm use %s] as root", local.JdkHomeLinkPath, oldJdks, strings.Join(strings.Split(config.Active, "_")[:2], " "))
+		}
+	}
+	installedChanged()
+	if config.Enabled {
+		local.SetupJavaHomeAndPath(homeLinkPath(), shimsPath(), config.System, config.Completion)
+	}
+	for _, n := range []string{"current", "shims", "locks"} {
+		os.RemoveAll(filepath.Join(old, n))
+	}
+	if err := os.Remove(old); err != nil {
+		color.Yellow("%s is not empty, left in place", old)
+	}
+	color.Green("jvm moved to %s, open a new terminal to use it", workPath)
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"github.com/fatih/color"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const vscodeRuntimes = "java.configuration.runtimes"
+
+var jdkEntryRe = regexp.MustCompile(`(?s)[ \t]*<jdk\b[^>]*>.*?</jdk>[ \t]*\n?`)
+var homePathRe = regexp.MustCompile(`<homePath value="([^"]*)"`)
+var emptyJdkTableRe = regexp.MustCompile(`<component name="ProjectJdkTable"\s*/>`)
+
+// managedHome 判断ide配置中的jdk路径是否由jvm管理
+func managedHome(p string) bool {
+	hd, _ := os.UserHomeDir()
+	p = filepath.Clean(filepath.FromSlash(strings.ReplaceAll(p, "$USER_HOME$", hd)))
+	if movedJdkPath != "" && strings.HasPrefix(p, movedJdkPath+string(filepath.Separator)) {
+		return true
+	}
+	return strings.HasPrefix(p, jdkPath+string(filepath.Separator))
+}
+
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// intellijTables 返回所有IntelliJ IDEA配置目录下的 jdk.table.xml
+func intellijTables() []string {
+	cfg, err := os.UserConfigDir()
+	if err != nil {
+		return nil
+	}
+	dirs, _ := filepath.Glob(filepath.Join(cfg, "JetBrains", "*"))
+	var ret []string
+	for _, d := range dirs {
+		n := filepath.Base(d)
+		if strings.HasPrefix(n, "IntelliJIdea") || strings.HasPrefix(n, "IdeaIC") {
+			ret = append(ret, filepath.Join(d, "options", "jdk.table.xml"))
+		}
+	}
+	return ret
+}
+
+// writeIntellijTable 用已安装的jdk替换 jdk.table.xml 中由jvm管理的jdk，sync 为 true 时只更新已有jvm条目的文件
+func writeIntellijTable(path string, sync bool) error {
+	old := readText(path)
+	content := old
+	if strings.TrimSpace(content) == "" {
+		content = "<application>\n  <component name=\"ProjectJdkTable\">\n  </component>\n</application>\n"
+	}
+	managed := false
+	content = jdkEntryRe.ReplaceAllStringFunc(content, func(e string) string {
+		if m := homePathRe.FindStringSubmatch(e); m != nil && managedHome(m[1]) {
+			managed = true
+			return ""
+		}
+		return e
+	})
+	if sync && !managed {
+		return nil
+	}
+	content = emptyJdkTableRe.ReplaceAllString(content, "<component name=\"ProjectJdkTable\">\n  </component>")
+	c := strings.Index(content, `<component name="ProjectJdkTable"`)
+	if c < 0 {
+		i := strings.LastIndex(content, "</application>")
+		if i < 0 {
+			return fmt.Errorf("%s: </application> not found", path)
+		}
+		content = content[:i] + "  <component name=\"ProjectJdkTable\">\n  </component>\n" + content[i:]
+		c = i
+	}
+	e := strings.Index(content[c:], "</component>")
+	if e < 0 {
+		return fmt.Errorf("%s: </component> not found", path)
+	}
+	ls := strings.LastIndex(content[:c+e], "\n") + 1
+
+	var b strings.Builder
+	for _, key := range installedJdks() {
+		ns := strings.Split(key, "_")
+		b.WriteString(fmt.Sprintf(`    <jdk version="2">
+      <name value="jvm-%s-%s" />
+      <type value="JavaSDK" />
+      <version value="%s" />
+      <homePath value="%s" />
+      <roots>
+        <annotationsPath>
+          <root type="composite" />
+        </annotationsPath>
+        <classPath>
+          <root type="composite" />
+        </classPath>
+        <javadocPath>
+          <root type="composite" />
+        </javadocPath>
+        <sourcePath>
+          <root type="composite" />
+        </sourcePath>
+      </roots>
+      <additional />
+    </jdk>
+`, ns[0], ns[1], xmlEscape(fmt.Sprintf("java version \"%s\"", jdkFullVersion(key))), xmlEscape(filepath.ToSlash(filepath.Join(jdkPath, key)))))
+	}
+	content = content[:ls] + b.String() + content[ls:]
+	return writeManaged(path, old, content, managed)
+}
+
+func vscodeSettingsPath() string {
+	cfg, _ := os.UserConfigDir()
+	return filepath.Join(cfg, "Code", "User", "settings.json")
+}
+
+// vscodeRuntime java.configuration.runtimes 中的一项
+type vscodeRuntime struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Default bool   `json:"default,omitempty"`
+}
+
+// writeVscodeSettings 用已安装的jdk替换 java.configuration.runtimes 中由jvm管理的条目，sync 为 true 时只更新已有jvm条目的文件，
+// 只改写这一项的值，文件中的注释、顺序和格式保持不变
+func writeVscodeSettings(path string, sync bool) error {
+	old := readText(path)
+	content := old
+	if strings.TrimSpace(content) == "" {
+		content = "{\n}\n"
+	}
+	ms, _, err := parseJSONCObject(content)
+	if err != nil {
+		return fmt.Errorf("%s: %s, edit %s by hand", path, err, vscodeRuntimes)
+	}
+	indent := "    "
+	if len(ms) > 0 {
+		indent = lineIndent(content, ms[0].keyStart)
+	}
+	var entries []string
+	for _, m := range ms {
+		if m.key != vscodeRuntimes {
+			continue
+		}
+		indent = lineIndent(content, m.keyStart)
+		if entries, err = jsoncElements(content[m.start:m.end]); err != nil {
+			return fmt.Errorf("%s: %s, edit %s by hand", path, err, vscodeRuntimes)
+		}
+	}
+	var runtimes []string
+	managed := false
+	hasDefault := false
+	for _, e := range entries {
+		var r vscodeRuntime
+		if json.Unmarshal([]byte(jsoncStrip(e)), &r) == nil {
+			if r.Path != "" && managedHome(r.Path) {
+				managed = true
+				continue
+			}
+			hasDefault = hasDefault || r.Default
+		}
+		runtimes = append(runtimes, e)
+	}
+	if sync && !managed {
+		return nil
+	}
+	// 每个 JavaSE-<n> 只能有一个运行时，优先使用当前激活的jdk
+	act := config.Active
+	keys := installedJdks()
+	if contains(keys, act) {
+		keys = append([]string{act}, keys...)
+	}
+	seen := map[string]bool{}
+	for _, key := range keys {
+		ns := strings.Split(key, "_")
+		name := "JavaSE-" + ns[1]
+		if ns[1] == "8" {
+			name = "JavaSE-1.8"
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		data, err := json.MarshalIndent(vscodeRuntime{Name: name, Path: filepath.Join(jdkPath, key), Default: key == act && !hasDefault}, indent+"    ", "    ")
+		if err != nil {
+			return err
+		}
+		runtimes = append(runtimes, string(data))
+	}
+	value := "[]"
+	if len(runtimes) > 0 {
+		value = "[\n" + indent + "    " + strings.Join(runtimes, ",\n"+indent+"    ") + "\n" + indent + "]"
+	}
+	if content, err = setJSONCMember(content, vscodeRuntimes, value, indent); err != nil {
+		return fmt.Errorf("%s: %s, edit %s by hand", path, err, vscodeRuntimes)
+	}
+	return writeManaged(path, old, content, managed)
+}
+
+// syncIdes 安装或删除jdk后更新已经由jvm写入过的ide配置
+func syncIdes() {
+	for _, p := range intellijTables() {
+		if err := writeIntellijTable(p, true); err != nil {
+			color.Red("update %s fail:%s", p, err)
+		}
+	}
+	if p := vscodeSettingsPath(); pathExist(p) {
+		if err := writeVscodeSettings(p, true); err != nil {
+			color.Red("update %s fail:%s", p, err)
+		}
+	}
+}
+
+// ideIntellij 把已安装的jdk注册到所有IntelliJ IDEA配置中，之后安装jdk时自动更新
+func ideIntellij(subs []string) {
+	ps := intellijTables()
+	if len(ps) == 0 {
+		failf("no IntelliJ IDEA config dir found, start the ide once first")
+		return
+	}
+	for _, p := range ps {
+		if err := writeIntellijTable(p, false); err != nil {
+			failf("update %s fail:%s", p, err)
+			continue
+		}
+		color.Green("%d jdk(s) written to %s", len(installedJdks()), p)
+	}
+	color.Yellow("restart IntelliJ IDEA to pick them up, a running ide may overwrite the file on exit")
+}
+
+// ideVscode 把已安装的jdk注册到VS Code设置中，之后安装jdk时自动更新
+func ideVscode(subs []string) {
+	p := vscodeSettingsPath()
+	if err := writeVscodeSettings(p, false); err != nil {
+		failf("update %s fail:%s", p, err)
+		return
+	}
+	color.Green("%d jdk(s) written to %s", len(installedJdks()), p)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"github.com/dtdyq/jvm/local"
+	"github.com/fatih/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// jdkInfo jvm info 输出的jdk详情
+type jdkInfo struct {
+	Key                string          `json:"key"`
+	Vendor             string          `json:"vendor"`
+	Major              string          `json:"major"`
+	JavaVersion        string          `json:"java_version"`
+	RuntimeVersion     string          `json:"runtime_version,omitempty"`
+	Implementor        string          `json:"implementor,omitempty"`
+	ImplementorVersion string          `json:"implementor_version,omitempty"`
+	VersionDate        string          `json:"version_date,omitempty"`
+	Modules            []string        `json:"modules"`
+	Jmods              bool            `json:"jmods"`
+	JavaFX             bool            `json:"javafx"`
+	CRaC               bool            `json:"crac"`
+	Path               string          `json:"path"`
+	Active             bool            `json:"active"`
+	Size               int64           `json:"size"`
+	URL                string          `json:"url,omitempty"`
+	SHA256             string          `json:"sha256,omitempty"`
+	Package            string          `json:"package,omitempty"`
+	InstalledAt        *time.Time      `json:"installed_at,omitempty"`
+	Processes          []local.Process `json:"processes"`
+}
+
+// collectInfo 从 release 文件、安装清单和jdk目录收集 key 的详情
+func collectInfo(key string) jdkInfo {
+	home := filepath.Join(jdkPath, key)
+	rel := readRelease(home)
+	ns := strings.Split(key, "_")
+	info := jdkInfo{
+		Key:                key,
+		Vendor:             ns[0],
+		Major:              ns[1],
+		JavaVersion:        jdkFullVersion(key),
+		RuntimeVersion:     rel["JAVA_RUNTIME_VERSION"],
+		Implementor:        rel["IMPLEMENTOR"],
+		ImplementorVersion: rel["IMPLEMENTOR_VERSION"],
+		VersionDate:        rel["JAVA_VERSION_DATE"],
+		Modules:            strings.Fields(rel["MODULES"]),
+		Jmods:              pathExist(filepath.Join(home, "jmods")),
+		Path:               home,
+		Active:             key == config.Active,
+		Size:               dirSize(home),
+	}
+	if info.Modules == nil {
+		info.Modules = []string{}
+	}
+	info.JavaFX = contains(info.Modules, "javafx.base") || pathExist(filepath.Join(home, "lib", "javafx.properties"))
+	info.CRaC = contains(info.Modules, "jdk.crac") || pathExist(filepath.Join(home, "lib", "criu"))
+	if m, ok := readManifest(key); ok {
+		info.URL = m.URL
+		info.SHA256 = m.SHA256
+		info.Package = m.Package
+		info.InstalledAt = &m.InstalledAt
+	}
+	// 接管的jdk是链接，进程中看到的是链接指向的目录
+	dir := home
+	if p, err := filepath.EvalSymlinks(home); err == nil {
+		dir = p
+	}
+	ps, err := local.ProcessesUnder(dir)
+	if err != nil {
+		color.New(color.FgYellow).Fprintf(os.Stderr, "list processes fail:%s\n", err)
+	}
+	info.Processes = ps
+	if info.Processes == nil {
+		info.Processes = []local.Process{}
+	}
+	return info
+}
+
+// infoJdk 显示已安装jdk的详细信息
+func infoJdk(subs []string) {
+	rest := subs
+	if len(rest) == 0 {
+		if config.Active == "" {
+			usagef("no jdk activated, give a version,use [jvm help info] for detail")
+			return
+		}
+		ns := strings.Split(config.Active, "_")
+		rest = []string{ns[1], ns[0]}
+	}
+	key, ok := parseVersionVendor(rest)
+	if !ok {
+		return
+	}
+	if !pathExist(filepath.Join(jdkPath, key)) {
+		failCode(exitNotInstalled, "%s not installed, use [jvm inst %s] first", key, strings.Join(rest, " "))
+		return
+	}
+	info := collectInfo(key)
+	if jsonOutput() {
+		printJSON("info", info)
+		return
+	}
+	field := func(name, format string, a ...interface{}) {
+		color.New(color.FgCyan).Printf("  %-16s", name)
+		color.White(format, a...)
+	}
+	yes := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "no"
+	}
+	field("version", "%s [%s] %s", info.Major, info.Vendor, info.JavaVersion)
+	if info.RuntimeVersion != "" {
+		field("runtime", "%s", info.RuntimeVersion)
+	}
+	if info.Implementor != "" {
+		field("implementor", "%s %s", info.Implementor, info.ImplementorVersion)
+	}
+	if info.VersionDate != "" {
+		field("release date", "%s", info.VersionDate)
+	}
+	field("path", "%s", info.Path)
+	field("active", "%s", yes(info.Active))
+	field("size", "%s", formatSize(info.Size))
+	field("jmods", "%s", yes(info.Jmods))
+	field("javafx", "%s", yes(info.JavaFX))
+	field("crac", "%s", yes(info.CRaC))
+	field("modules", "%d %s", len(info.Modules), strings.Join(info.Modules, " "))
+	if info.URL != "" {
+		field("source", "%s", info.URL)
+	}
+	if info.SHA256 != "" {
+		field("sha256", "%s", info.SHA256)
+	}
+	if info.InstalledAt != nil {
+		field("installed", "%s (%s)", info.InstalledAt.Local().Format("2006-01-02 15:04"), info.Package)
+	}
+	if len(info.Processes) == 0 {
+		field("processes", "none")
+	}
+	for i, p := range info.Processes {
+		name := ""
+		if i == 0 {
+			name = "processes"
+		}
+		field(name, "%d %s", p.Pid, p.Exe)
+	}
+}
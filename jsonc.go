@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// 按位置编辑带注释和尾随逗号的json(VS Code 的 settings.json)，只改动需要修改的值，其余内容原样保留
+
+// jsoncMember 根对象中的一个成员，keyStart 为键的位置，start/end 为值的范围
+type jsoncMember struct {
+	key      string
+	keyStart int
+	start    int
+	end      int
+}
+
+// jsoncSkipSpace 跳过空白和注释，返回下一个有效字符的位置
+func jsoncSkipSpace(s string, i int) int {
+	for i < len(s) {
+		switch {
+		case s[i] == ' ' || s[i] == '\t' || s[i] == '\r' || s[i] == '\n':
+			i++
+		case strings.HasPrefix(s[i:], "\ufeff"):
+			i += len("\ufeff")
+		case strings.HasPrefix(s[i:], "//"):
+			j := strings.IndexByte(s[i:], '\n')
+			if j < 0 {
+				return len(s)
+			}
+			i += j + 1
+		case strings.HasPrefix(s[i:], "/*"):
+			j := strings.Index(s[i+2:], "*/")
+			if j < 0 {
+				return len(s)
+			}
+			i += j + 4
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// jsoncSkipString 返回从 i 开始的字符串结束后的位置
+func jsoncSkipString(s string, i int) (int, error) {
+	for j := i + 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '"':
+			return j + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated string at offset %d", i)
+}
+
+// jsoncSkipValue 返回从 i 开始的值结束后的位置
+func jsoncSkipValue(s string, i int) (int, error) {
+	if i >= len(s) {
+		return 0, fmt.Errorf("unexpected end of json")
+	}
+	switch s[i] {
+	case '"':
+		return jsoncSkipString(s, i)
+	case '{', '[':
+		depth := 0
+		for j := i; j < len(s); {
+			if j = jsoncSkipSpace(s, j); j >= len(s) {
+				break
+			}
+			switch s[j] {
+			case '"':
+				e, err := jsoncSkipString(s, j)
+				if err != nil {
+					return 0, err
+				}
+				j = e
+				continue
+			case '{', '[':
+				depth++
+			case '}', ']':
+				if depth--; depth == 0 {
+					return j + 1, nil
+				}
+			}
+			j++
+		}
+		return 0, fmt.Errorf("unterminated %c at offset %d", s[i], i)
+	}
+	j := i
+	for j < len(s) && !strings.ContainsRune(" \t\r\n,:{}[]\"/", rune(s[j])) {
+		j++
+	}
+	if j == i {
+		return 0, fmt.Errorf("unexpected %q at offset %d", s[i], i)
+	}
+	return j, nil
+}
+
+// parseJSONCObject 返回根对象的成员和左大括号的位置
+func parseJSONCObject(s string) ([]jsoncMember, int, error) {
+	open := jsoncSkipSpace(s, 0)
+	if open >= len(s) || s[open] != '{' {
+		return nil, 0, fmt.Errorf("not a json object")
+	}
+	var ms []jsoncMember
+	for i := open + 1; ; {
+		if i = jsoncSkipSpace(s, i); i >= len(s) {
+			return nil, 0, fmt.Errorf("unexpected end of json")
+		}
+		switch s[i] {
+		case '}':
+			return ms, open, nil
+		case ',':
+			i++
+			continue
+		case '"':
+		default:
+			return nil, 0, fmt.Errorf("unexpected %q at offset %d", s[i], i)
+		}
+		ke, err := jsoncSkipString(s, i)
+		if err != nil {
+			return nil, 0, err
+		}
+		var key string
+		if err = json.Unmarshal([]byte(s[i:ke]), &key); err != nil {
+			return nil, 0, err
+		}
+		colon := jsoncSkipSpace(s, ke)
+		if colon >= len(s) || s[colon] != ':' {
+			return nil, 0, fmt.Errorf("missing : after %s at offset %d", s[i:ke], i)
+		}
+		vs := jsoncSkipSpace(s, colon+1)
+		ve, err := jsoncSkipValue(s, vs)
+		if err != nil {
+			return nil, 0, err
+		}
+		ms = append(ms, jsoncMember{key: key, keyStart: i, start: vs, end: ve})
+		i = ve
+	}
+}
+
+// jsoncElements 返回数组中每个元素的原始内容，不是数组时返回空
+func jsoncElements(s string) ([]string, error) {
+	i := jsoncSkipSpace(s, 0)
+	if i >= len(s) || s[i] != '[' {
+		return nil, nil
+	}
+	var es []string
+	for i++; ; {
+		if i = jsoncSkipSpace(s, i); i >= len(s) {
+			return nil, fmt.Errorf("unterminated array")
+		}
+		switch s[i] {
+		case ']':
+			return es, nil
+		case ',':
+			i++
+			continue
+		}
+		e, err := jsoncSkipValue(s, i)
+		if err != nil {
+			return nil, err
+		}
+		es = append(es, s[i:e])
+		i = e
+	}
+}
+
+// jsoncStrip 去掉注释和尾随逗号，得到可以用 encoding/json 解析的内容
+func jsoncStrip(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '"':
+			j, err := jsoncSkipString(s, i)
+			if err != nil {
+				j = len(s)
+			}
+			b.WriteString(s[i:j])
+			i = j
+		case strings.HasPrefix(s[i:], "//") || strings.HasPrefix(s[i:], "/*"):
+			i = jsoncSkipSpace(s, i)
+			b.WriteByte(' ')
+		case s[i] == ',':
+			if j := jsoncSkipSpace(s, i+1); j < len(s) && (s[j] == '}' || s[j] == ']') {
+				i++
+				continue
+			}
+			b.WriteByte(',')
+			i++
+		default:
+			b.WriteByte(s[i])
+			i++
+		}
+	}
+	return b.String()
+}
+
+// lineIndent 返回位置 i 所在行的缩进
+func lineIndent(s string, i int) string {
+	line := s[strings.LastIndex(s[:i], "\n")+1 : i]
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// setJSONCMember 把根对象中 key 的值替换为 value，没有时作为最后一个成员插入，indent 为成员的缩进
+func setJSONCMember(s, key, value, indent string) (string, error) {
+	ms, open, err := parseJSONCObject(s)
+	if err != nil {
+		return "", err
+	}
+	for _, m := range ms {
+		if m.key == key {
+			return s[:m.start] + value + s[m.end:], nil
+		}
+	}
+	k, _ := json.Marshal(key)
+	at, sep := open+1, ""
+	if n := len(ms); n > 0 {
+		at, sep = ms[n-1].end, ","
+		if j := jsoncSkipSpace(s, at); j < len(s) && s[j] == ',' {
+			at, sep = j+1, ""
+		}
+	}
+	return s[:at] + sep + "\n" + indent + string(k) + ": " + value + s[at:], nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetJSONCMember(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{"empty object", "{\n}\n", "{\n  \"k\": 1\n}\n", false},
+		{"insert after last member", "{\n  \"b\": true,\n  \"a\": \"x\"\n}\n", "{\n  \"b\": true,\n  \"a\": \"x\",\n  \"k\": 1\n}\n", false},
+		{"insert after trailing comma", "{\n  \"a\": 1,\n}\n", "{\n  \"a\": 1,\n  \"k\": 1\n}\n", false},
+		{"replace in place", "{\n  \"b\": 2,\n  \"k\": [0],\n  \"a\": 3\n}\n", "{\n  \"b\": 2,\n  \"k\": 1,\n  \"a\": 3\n}\n", false},
+		{"comments kept", "// user settings\n{\n  /* theme */ \"a\": \"//x\", // note\n  \"k\": {\"n\": [1, 2]} // old\n}\n", "// user settings\n{\n  /* theme */ \"a\": \"//x\", // note\n  \"k\": 1 // old\n}\n", false},
+		{"escaped quote in key", "{\"a\\\"k\": 0}", "{\"a\\\"k\": 0,\n  \"k\": 1}", false},
+		{"not an object", "[1]", "", true},
+		{"unterminated", "{\"a\": [1, 2}", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := setJSONCMember(tt.content, "k", "1", "  ")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONCElements(t *testing.T) {
+	got, err := jsoncElements("[\n  {\"a\": [1, {\"b\": \"]\"}]}, // first\n  \"x\",\n  3,\n]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"{\"a\": [1, {\"b\": \"]\"}]}", "\"x\"", "3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestJSONCStrip(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"{\"a\": 1, // c\n}", "{\"a\": 1  }"},
+		{"[1, /* c */ 2,]", "[1,  2]"},
+		{"{\"u\": \"http://x\"}", "{\"u\": \"http://x\"}"},
+	}
+	for _, tt := range tests {
+		if got := jsoncStrip(tt.in); got != tt.want {
+			t.Errorf("jsoncStrip(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -3,8 +3,8 @@ package main
 import (
 	"archive/tar"
 	"archive/zip"
-	"bufio"
 	"compress/gzip"
+	"flag"
 	"fmt"
 	"github.com/dtdyq/jvm/local"
 	"github.com/fatih/color"
@@ -12,64 +12,336 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
-	"strconv"
+	"sort"
 	"strings"
 )
 
 //=============define begin=================//
 
 type CmdInfo struct {
-	cmd  string
+	cmd string
+	// args 位置参数说明，如 <version|alias> [vendor]
+	args string
 	desc string
+	// examples jvm help <cmd> 中的示例
+	examples []string
+	// flags 定义命令支持的参数
+	flags func(fs *flag.FlagSet)
+	// subs 子命令，如 jvm alias set
+	subs []CmdInfo
 	proc func(subs []string)
+	// lock 修改jvm状态的命令，执行期间持有状态锁
+	lock bool
+	// json 支持 --json 输出
+	json bool
+	// always 未执行 jvm on 时也可以使用
+	always bool
+	// hidden 不在帮助中显示
+	hidden bool
 }
 
 var commands = []CmdInfo{
 	{
 		cmd:  "help",
+		args: "[command] [sub command]",
 		desc: "for help info",
 	},
 	{
 		cmd:  "on",
-		desc: "enable java version manager[will remove old jdk envs]",
-		proc: enableJvm,
+		desc: "enable java version manager[will check old jdk envs]",
+		flags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&flagSystem, "system", false, "link the active jdk under "+local.JdkHomeLinkPath+" for all users[needs root]")
+			fs.BoolVar(&assumeYes, "yes", false, "comment out conflicting java envs and adopt their jdks without asking")
+			fs.BoolVar(&flagCompletion, "completion", false, "also load shell completion in the profile, kept until [jvm off]")
+		},
+		examples: []string{"jvm on", "jvm on --completion", "sudo jvm on --system --yes"},
+		proc:     enableJvm,
+		lock:     true,
+		always:   true,
 	},
 	{
-		cmd:  "off",
-		desc: "disable java version manager,revert old env[try]",
-		proc: disableJvm,
+		cmd:    "off",
+		desc:   "disable java version manager,remove jvm block from shell profiles",
+		proc:   disableJvm,
+		lock:   true,
+		always: true,
 	},
 	{
-		cmd:  "cur",
-		desc: "current activated jdk",
-		proc: currentActiveJdk,
+		cmd:      "cur",
+		args:     "[version|alias] [vendor]",
+		desc:     "the jdk in effect here, where it is selected and whether JAVA_HOME and java on PATH match it\nwith a version, whether the given one is installed and active",
+		flags:    func(fs *flag.FlagSet) { vendorFlag(fs); archFlag(fs) },
+		examples: []string{"jvm cur", "jvm cur work", "jvm cur 21 --vendor openjdk --json"},
+		proc:     currentActiveJdk,
+		json:     true,
 	},
 	{
-		cmd:  "list",
-		desc: "all installed jdk",
-		proc: listInstalledJdk,
+		cmd:      "list",
+		args:     "[version|alias] [vendor]",
+		desc:     "all installed jdk, or only the given one",
+		flags:    func(fs *flag.FlagSet) { vendorFlag(fs) },
+		examples: []string{"jvm list", "jvm list --vendor graal", "jvm list --json"},
+		proc:     listInstalledJdk,
+		json:     true,
+	},
+	{
+		cmd:      "ls-remote",
+		args:     "[version] [vendor]",
+		desc:     "jdks that can be installed on this system",
+		flags:    func(fs *flag.FlagSet) { vendorFlag(fs); archFlag(fs) },
+		examples: []string{"jvm ls-remote", "jvm ls-remote 21", "jvm ls-remote --vendor openjdk --arch arm64"},
+		proc:     listRemoteJdk,
+		json:     true,
 	},
 	{
 		cmd:  "inst",
-		desc: "<version> [param] version like 21.0.1 or lts for latest lts version,\nparam:jdk vendor [openjdk|graal|oraclejdk|liberica(default)]",
-		proc: instJdk,
+		args: "<version|alias> [vendor]",
+		desc: "install a jdk\nversion like 21.0.1 or lts-latest for latest lts version,\nvendor [openjdk|graal|oracle|liberica(default)]",
+		flags: func(fs *flag.FlagSet) {
+			vendorFlag(fs)
+			archFlag(fs)
+			fs.BoolVar(&flagForce, "force", false, "download and install again even if already installed")
+			fs.BoolVar(&flagDryRun, "dry-run", false, "only show what would be downloaded and where it goes")
+		},
+		examples: []string{"jvm inst 21", "jvm inst 17 openjdk", "jvm inst lts-latest --vendor graal", "jvm inst 17 --arch x64 --dry-run"},
+		proc:     instJdk,
+		json:     true,
+	},
+	{
+		cmd:      "outdated",
+		args:     "[version|alias] [vendor]",
+		desc:     "installed jdks with a newer patch release in the catalog, what [jvm upgrade] would install",
+		flags:    func(fs *flag.FlagSet) { vendorFlag(fs) },
+		examples: []string{"jvm outdated", "jvm outdated 17 --json"},
+		proc:     outdatedJdks,
+		json:     true,
+	},
+	{
+		cmd:  "upgrade",
+		args: "[version|alias] [vendor]",
+		desc: "install the newest patch release of installed jdks, a version alone upgrades all its vendors\njvm manages one patch per vendor and major, the old patch is removed, or moved to\n<jdks>/superseded/<key>-<version> with --keep; the active jdk, shims, toolchains and ides follow\nand aliases naming the old patch are moved to the new one",
+		flags: func(fs *flag.FlagSet) {
+			vendorFlag(fs)
+			fs.BoolVar(&flagForce, "force", false, "replace jdks even if processes are running them")
+			fs.BoolVar(&flagKeep, "keep", false, "keep the old patch in <jdks>/superseded instead of removing it")
+		},
+		examples: []string{"jvm upgrade", "jvm upgrade 17", "jvm upgrade --vendor openjdk", "jvm upgrade 17 --keep"},
+		proc:     upgradeJdk,
+		json:     true,
 	},
 	{
-		cmd:  "use",
-		desc: "<version> <vendor> use the specify jdk",
-		proc: useJdk,
+		cmd:      "info",
+		args:     "[version|alias] [vendor]",
+		desc:     "show details of an installed jdk, default the active one\nrelease info, modules, jmods, javafx/crac, size, source and the processes running it",
+		flags:    func(fs *flag.FlagSet) { vendorFlag(fs); archFlag(fs) },
+		examples: []string{"jvm info", "jvm info 17 --json"},
+		proc:     infoJdk,
+		json:     true,
+	},
+	{
+		cmd:      "use",
+		args:     "<version|alias> [vendor]",
+		desc:     "use the specify jdk, latest and lts-latest pick the newest installed",
+		flags:    func(fs *flag.FlagSet) { vendorFlag(fs); archFlag(fs) },
+		examples: []string{"jvm use 17", "jvm use 21 graal", "jvm use work"},
+		proc:     useJdk,
+		lock:     true,
+	},
+	{
+		cmd:  "env",
+		args: "[version|alias] [vendor]",
+		desc: "print shell code activating the jdk for the current session only\nwithout version the project jdk or the global one is used,\na given version pins the shell with JVM_VERSION until it is unset",
+		flags: func(fs *flag.FlagSet) {
+			vendorFlag(fs)
+			archFlag(fs)
+			fs.StringVar(&flagShell, "shell", "", "shell to print code for bash|zsh|fish|sh, default the login shell")
+			fs.BoolVar(&flagHook, "hook", false, "called by the cd hook of [jvm init], switches to the project jdk")
+		},
+		examples: []string{"eval \"$(jvm env 17)\"", "jvm env 21 --shell fish | source"},
+		proc:     envJdk,
+		always:   true,
+	},
+	{
+		cmd:  "exec",
+		args: "<version|alias> [vendor] -- <command> [args]",
+		desc: "run a command with the jdk without changing the active one",
+		flags: func(fs *flag.FlagSet) {
+			vendorFlag(fs)
+			archFlag(fs)
+			fs.BoolVar(&flagInstall, "install", false, "install the jdk first if missing")
+		},
+		examples: []string{"jvm exec 21 -- mvn -v", "jvm exec 17 --install -- java -version"},
+		proc:     execJdk,
+		always:   true,
+	},
+	{
+		cmd:      "shell",
+		args:     "<version|alias> [vendor]",
+		desc:     "start $SHELL with the jdk activated\nJVM_ACTIVE is set for prompts, exit it to return to the previous env",
+		flags:    func(fs *flag.FlagSet) { vendorFlag(fs); archFlag(fs) },
+		examples: []string{"jvm shell 11"},
+		proc:     shellJdk,
+		always:   true,
+	},
+	{
+		cmd:      "local",
+		args:     "[version|alias] [vendor]",
+		desc:     "pin the jdk of the current directory in " + projectFile + "\nalso reads .java-version, .sdkmanrc and .tool-versions; no args shows the pinned one",
+		flags:    func(fs *flag.FlagSet) { vendorFlag(fs) },
+		examples: []string{"jvm local 17", "jvm local"},
+		proc:     localJdk,
+	},
+	{
+		cmd:  "rehash",
+		desc: "regenerate the java, javac, jar... shims from all installed jdks\nshims pick the jdk from JVM_VERSION, the project version file or the global one",
+		proc: rehashJdk,
+		lock: true,
+	},
+	{
+		cmd:  "toolchains",
+		desc: "write installed jdks into the toolchain config of a build tool, kept in sync on install",
+		subs: []CmdInfo{
+			{
+				cmd:  "maven",
+				desc: "write installed jdks into ~/.m2/toolchains.xml",
+				proc: toolchainsMaven,
+			},
+			{
+				cmd:  "gradle",
+				desc: "write installed jdks into ~/.gradle/gradle.properties",
+				flags: func(fs *flag.FlagSet) {
+					fs.BoolVar(&flagNoDownload, "no-download", false, "stop gradle from downloading its own jdks")
+				},
+				examples: []string{"jvm toolchains gradle --no-download"},
+				proc:     toolchainsGradle,
+			},
+		},
+		examples: []string{"jvm toolchains maven", "jvm toolchains gradle --no-download"},
+		lock:     true,
+	},
+	{
+		cmd:  "ide",
+		desc: "register installed jdks in an ide, kept in sync on install",
+		subs: []CmdInfo{
+			{
+				cmd:  "intellij",
+				desc: "register installed jdks in jdk.table.xml of every IntelliJ IDEA config dir",
+				proc: ideIntellij,
+			},
+			{
+				cmd:  "vscode",
+				desc: "register installed jdks in java.configuration.runtimes of VS Code settings",
+				proc: ideVscode,
+			},
+		},
+		examples: []string{"jvm ide intellij", "jvm ide vscode"},
+		lock:     true,
+	},
+	{
+		cmd:  "config",
+		desc: "show or change settings stored in the user config\nresolved from JVM_* env > .jvm.json of the project > user config > /etc/jvm/config.json,\na project can only set " + strings.Join(projectSettings(), ", "),
+		subs: []CmdInfo{
+			{
+				cmd:  "list",
+				desc: "all settings with their value and where it comes from",
+				proc: configList,
+			},
+			{
+				cmd:  "get",
+				args: "<key>",
+				desc: "print the value of a setting",
+				proc: configGet,
+			},
+			{
+				cmd:  "set",
+				args: "<key> <value>",
+				desc: "store a setting in the user config",
+				proc: configSet,
+			},
+			{
+				cmd:  "unset",
+				args: "<key>",
+				desc: "remove a setting from the user config",
+				proc: configUnset,
+			},
+		},
+		examples: []string{"jvm config list", "jvm config set default-vendor openjdk", "jvm config get mirror"},
+		lock:     true,
+		always:   true,
+	},
+	{
+		cmd:  "alias",
+		desc: "name a jdk selection, the name works wherever a version does\naliases are stored in the user config, latest and lts-latest resolve to the newest matching jdk when used",
+		subs: []CmdInfo{
+			{
+				cmd:  "set",
+				args: "<name> <version> [vendor]",
+				desc: "create or change an alias",
+				proc: aliasSet,
+			},
+			{
+				cmd:  "rm",
+				args: "<name>",
+				desc: "remove an alias",
+				proc: aliasRm,
+			},
+			{
+				cmd:  "list",
+				desc: "all aliases and the jdk they resolve to",
+				proc: aliasList,
+			},
+		},
+		examples: []string{"jvm alias set work 17.0.10-liberica", "jvm alias set newest lts-latest", "jvm use work"},
+		lock:     true,
+	},
+	{
+		cmd:      "init",
+		args:     "[bash|zsh|fish|sh]",
+		desc:     "print the shell integration, after eval \"$(jvm init bash)\"\n[jvm use] only affects the current shell and cd switches to the project jdk",
+		examples: []string{"eval \"$(jvm init bash)\"", "jvm init fish | source"},
+		proc:     initShell,
+		always:   true,
+	},
+	{
+		cmd:      "doctor",
+		desc:     "check why java may not be the selected jdk and suggest fixes\nchecks the config, shell profile, JAVA_HOME, the home link, java on PATH, conflicting java envs,\nthe system link permission, whether the active jdk runs and its size against the install manifest",
+		examples: []string{"jvm doctor", "jvm doctor --json"},
+		proc:     doctorJdk,
+		json:     true,
+		always:   true,
+	},
+	{
+		cmd:      "completion",
+		args:     "[bash|zsh|fish]",
+		desc:     "print the shell completion script\nversions, vendors and aliases are completed from installed and downloadable jdks,\n[jvm on --completion] loads it from the shell profile",
+		examples: []string{"eval \"$(jvm completion bash)\"", "jvm completion fish > ~/.config/fish/completions/jvm.fish"},
+		proc:     completionJdk,
+		always:   true,
+	},
+	{
+		cmd:    completeCmd,
+		args:   "-- [word...]",
+		desc:   "print the completions of the last word, used by the completion scripts",
+		hidden: true,
 	},
 }
 
 // vendor_version_system_arch
 //
-//vendor:liberica openjdk oracle graal
+//vendor:liberica openjdk oracle graal local(jdk adopted from the system, not installable)
 //version:8 11 17 21
 //system:windows linux macos
 //arch:x32 x64 arch64 arch32
 var supportVendor = []string{"liberica", "openjdk", "oracle", "graal"}
+
+// adoptedVendor jvm on 接管的系统jdk的厂商，只能选择不能下载
+const adoptedVendor = "local"
+
+// selectVendor 选择已安装的jdk时可以使用的厂商
+var selectVendor = append(append([]string{}, supportVendor...), adoptedVendor)
 var supportVersion = []string{"8", "11", "17", "21"}
 var supportSys = []string{"windows", "linux", "macos"}
 var supportArch = []string{"x32", "x64", "arch64", "arch32"}
@@ -119,49 +391,25 @@ var jdks = map[string]string{
 	"openjdk_11_linux_x64":   "https://download.java.net/java/ga/jdk11/openjdk-11_linux-x64_bin.tar.gz",
 }
 
-const ckEnabled = "enabled"
-const ckActivated = "active"
-
-var config = map[string]string{}
 var workPath = ""
 var jdkPath = ""
 
 // =============define end=================//
-func init() {
-	tryInitEnv()
-}
-
 func main() {
+	tryInitEnv()
 	defer exit()
 	args := os.Args
-	if len(args) <= 1 {
-		help(commands)
+	if name, rest := shimName(args); name != "" {
+		runShim(name, rest)
 		return
 	}
-	mc := args[1]
-
-	if mc != "on" && mc != "off" {
-		if !getBoolConfig(ckEnabled, false) {
-			color.Yellow("before start,use jvm on to enable java version manager")
-			return
-		}
-	}
-	var b = false
-	for _, cmd := range commands {
-		if mc == "help" {
-			b = true
-			help(commands)
-			return
-		}
-		if mc == cmd.cmd {
-			b = true
-			cmd.proc(args[2:])
-			break
-		}
-	}
-	if !b {
-		help(commands)
+	args, err := parseGlobalFlags(args)
+	if err != nil {
+		usagef("%s", err)
+	} else {
+		dispatch(args)
 	}
+	exitWith()
 }
 
 //================setup start===================//
@@ -172,60 +420,47 @@ func tryInitEnv() {
 		color.Red("%s", err)
 		return
 	}
-	if !pathExist(filepath.Join(dir, ".jvm")) {
-		err = os.Mkdir(filepath.Join(dir, ".jvm"), os.ModePerm)
-		if err != nil {
-			color.Red("create work dir err:%s", err)
-			return
+	workPath, dataPath, cacheRoot = homeDirs(dir)
+	for _, p := range []string{workPath, dataPath} {
+		if !pathExist(p) {
+			err = os.MkdirAll(p, os.ModePerm)
+			if err != nil {
+				color.Red("create work dir err:%s", err)
+				return
+			}
 		}
 	}
-	workPath = filepath.Join(dir, ".jvm")
-	jdkPath = filepath.Join(dir, ".jvm", "jdks")
-	if !pathExist(jdkPath) {
-		err = os.Mkdir(jdkPath, os.ModePerm)
+	if legacyHome(dir) != "" {
+		err = withStateLock(func() {
+			// 等锁期间其他进程可能已经完成了迁移
+			if old := legacyHome(dir); old != "" {
+				moveHome(old)
+			}
+		})
 		if err != nil {
-			color.Red("create jdk dir err:%s", err)
-			return
+			color.Red("%s", err)
 		}
 	}
-	cfgFile := filepath.Join(dir, ".jvm", "jvm.cfg")
-	file, err := os.OpenFile(cfgFile, os.O_RDWR|os.O_CREATE, 0666)
-	if err != nil {
+	if err = loadConfig(); err != nil {
 		color.Red("%s", err)
-		return
 	}
-	r := bufio.NewScanner(file)
-	if err != nil {
-		color.Red("%s", err)
-		return
+	applySettings()
+	jdkPath = filepath.Join(dataPath, "jdks")
+	if d := getSetting("install-dir"); d != "" {
+		jdkPath = d
 	}
-	for r.Scan() {
-		text := r.Text()
-		if strings.TrimSpace(text) == "" {
-			continue
+	if !pathExist(jdkPath) {
+		err = os.MkdirAll(jdkPath, os.ModePerm)
+		if err != nil {
+			color.Red("create jdk dir err:%s", err)
+			return
 		}
-		kv := strings.SplitN(strings.TrimSpace(text), "=", 2)
-		config[kv[0]] = kv[1]
 	}
 }
 
+// exit 配置在 withStateLock 中保存，这里只释放残留的锁
 func exit() {
-	dir, _ := os.UserHomeDir()
-	cfgFile := filepath.Join(dir, ".jvm", "jvm.cfg")
-	file, err := os.OpenFile(cfgFile, os.O_WRONLY|os.O_CREATE, 0666)
-	if err != nil {
-		color.Red("exit:%s", err)
-		return
-	}
-	defer file.Close()
-	w := bufio.NewWriter(file)
-	defer w.Flush()
-	for k, v := range config {
-		_, err = w.WriteString(fmt.Sprintf("%s=%s\n", k, v))
-		if err != nil {
-			color.HiYellow("%s", err)
-		}
-	}
+	releaseLocks()
 }
 
 //=================setup end======================//
@@ -243,36 +478,15 @@ func pathExist(path string) bool {
 	return false
 }
 
-func getConfig(key string, def string) string {
-	val, exist := config[key]
-	if !exist {
-		return def
-	}
-	return val
-}
-
-func getBoolConfig(key string, def bool) bool {
-	ret, err := strconv.ParseBool(getConfig(key, strconv.FormatBool(def)))
-	if err != nil {
-		return def
-	}
-	return ret
-}
-
-func getI32Config(key string, def int32) int32 {
-	ret, err := strconv.Atoi(getConfig(key, strconv.Itoa(int(def))))
-	if err != nil {
-		return def
-	}
-	return int32(ret)
-}
-
 func downloadKeyBy(version string, vendor string) string {
 	system := runtime.GOOS
 	if system == "darwin" {
 		system = "macos"
 	}
 	arch := runtime.GOARCH
+	if flagArch != "" {
+		return fmt.Sprintf("%s_%s_%s_%s", vendor, version, system, flagArch)
+	}
 	switch arch {
 	case "386":
 		arch = "x32"
@@ -290,22 +504,55 @@ func downloadKeyBy(version string, vendor string) string {
 	return fmt.Sprintf("%s_%s_%s_%s", vendor, version, system, arch)
 }
 
-func extractRelevantDirs(tarball string) error {
+// openTar 打开 .tar.gz 安装包
+func openTar(tarball string) (*tar.Reader, func(), error) {
 	file, err := os.Open(tarball)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	defer file.Close()
-	target := filepath.Dir(tarball)
 	gzipReader, err := gzip.NewReader(file)
 	if err != nil {
-		color.Red("%s", err)
+		file.Close()
+		return nil, nil, err
 	}
-	defer gzipReader.Close()
+	return tar.NewReader(gzipReader), func() {
+		gzipReader.Close()
+		file.Close()
+	}, nil
+}
 
-	tarReader := tar.NewReader(gzipReader)
+// tarJdkRoot 找到安装包中 bin/java 所在的jdk根目录，macOS的包在 Contents/Home 下
+func tarJdkRoot(tarball string) (string, error) {
+	tarReader, closer, err := openTar(tarball)
+	if err != nil {
+		return "", err
+	}
+	defer closer()
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return "", fmt.Errorf("no bin/java found in %s", tarball)
+		}
+		if err != nil {
+			return "", err
+		}
+		if name := strings.TrimPrefix(header.Name, "./"); strings.HasSuffix(name, "/bin/java") {
+			return strings.TrimSuffix(name, "bin/java"), nil
+		}
+	}
+}
 
-	var relevantDirPrefix string
+// extractRelevantDirs 把安装包中jdk根目录下的内容解压到 target
+func extractRelevantDirs(tarball, target string) error {
+	relevantDirPrefix, err := tarJdkRoot(tarball)
+	if err != nil {
+		return err
+	}
+	tarReader, closer, err := openTar(tarball)
+	if err != nil {
+		return err
+	}
+	defer closer()
 
 	for {
 		header, err := tarReader.Next()
@@ -318,48 +565,88 @@ func extractRelevantDirs(tarball string) error {
 		case header == nil:
 			continue
 		}
-		name := header.Name
-		if relevantDirPrefix == "" && strings.Contains(name, "/bin/") {
-			relevantDirPrefix = strings.Split(name, "/bin/")[0]
+		name := strings.TrimPrefix(header.Name, "./")
+		if !strings.HasPrefix(name, relevantDirPrefix) {
 			continue
 		}
-		if relevantDirPrefix != "" && strings.HasPrefix(name, relevantDirPrefix) {
-			targetPath := filepath.Join(target, strings.TrimPrefix(name, relevantDirPrefix))
+		targetPath := filepath.Join(target, strings.TrimPrefix(name, relevantDirPrefix))
+		if err := safeTarget(target, targetPath, header.Typeflag != tar.TypeSymlink); err != nil {
+			return fmt.Errorf("%s:%s", err, header.Name)
+		}
 
-			switch header.Typeflag {
-			case tar.TypeDir:
-				if err := os.MkdirAll(targetPath, 0755); err != nil {
-					return err
-				}
-			case tar.TypeReg:
-				if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
-					color.Red("%s", err)
-					return err
-				}
-				outFile, err := os.OpenFile(targetPath, os.O_CREATE|os.O_RDWR|os.O_TRUNC, os.FileMode(header.Mode))
-				if err != nil {
-					color.Red("%s", err)
-					return err
-				}
-				if _, err := io.Copy(outFile, tarReader); err != nil {
-					outFile.Close()
-					color.Red("%s", err)
-					return err
-				}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(targetPath, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+				color.Red("%s", err)
+				return err
+			}
+			outFile, err := os.OpenFile(targetPath, os.O_CREATE|os.O_RDWR|os.O_TRUNC, os.FileMode(header.Mode))
+			if err != nil {
+				color.Red("%s", err)
+				return err
+			}
+			if _, err := io.Copy(outFile, tarReader); err != nil {
 				outFile.Close()
+				color.Red("%s", err)
+				return err
 			}
+			outFile.Close()
+		case tar.TypeSymlink:
+			// jdk中的符号链接都是指向jdk内部的相对路径，如 legal 下的许可文件
+			if filepath.IsAbs(header.Linkname) || !withinDir(target, filepath.Join(filepath.Dir(targetPath), header.Linkname)) {
+				return fmt.Errorf("illegal link in archive:%s -> %s", header.Name, header.Linkname)
+			}
+			if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+				return err
+			}
+			if err := os.Symlink(header.Linkname, targetPath); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// withinDir 路径 p 是否为 root 或在 root 之下，只比较路径，不跟随链接
+func withinDir(root, p string) bool {
+	rel, err := filepath.Rel(root, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// safeTarget 检查解压的路径 p 在 root 之内，并且不会经过已经解压出来的符号链接写到 root 之外，
+// self 为 true 时 p 本身也不能是符号链接
+func safeTarget(root, p string, self bool) error {
+	if !withinDir(root, p) {
+		return fmt.Errorf("illegal path in archive")
+	}
+	rel, _ := filepath.Rel(root, p)
+	cs := strings.Split(rel, string(filepath.Separator))
+	if !self {
+		cs = cs[:len(cs)-1]
+	}
+	cur := root
+	for _, c := range cs {
+		if c == "." {
+			continue
+		}
+		cur = filepath.Join(cur, c)
+		if fi, err := os.Lstat(cur); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("illegal path through link in archive")
 		}
 	}
+	return nil
 }
 
 // unzipJDK 提取ZIP文件中的 'bin' 目录和它同级的其他目录或文件。
-func unzipJDK(src string) error {
+func unzipJDK(src, dest string) error {
 	r, err := zip.OpenReader(src)
 	if err != nil {
 		return err
 	}
 	defer r.Close()
-	dest := filepath.Dir(src)
 	// 找到 'bin' 目录在ZIP文件中的路径
 	var binPath string
 	for _, f := range r.File {
@@ -370,13 +657,16 @@ func unzipJDK(src string) error {
 	}
 
 	if binPath == "" {
-		return fmt.Errorf("没有在ZIP文件中找到 'bin' 目录")
+		return fmt.Errorf("no bin directory found in %s", src)
 	}
 
 	// 解压 'bin' 目录同级的所有文件和目录
 	for _, f := range r.File {
 		if strings.HasPrefix(f.Name, binPath) {
 			fPath := filepath.Join(dest, strings.TrimPrefix(f.Name, binPath))
+			if err = safeTarget(dest, fPath, true); err != nil {
+				return fmt.Errorf("%s:%s", err, f.Name)
+			}
 			if f.FileInfo().IsDir() {
 				os.MkdirAll(fPath, os.ModePerm)
 				continue
@@ -409,72 +699,188 @@ func unzipJDK(src string) error {
 	return nil
 }
 
-func downloadJdkTo(url, key string) {
-	req, _ := http.NewRequest("GET", url, nil)
-	resp, _ := http.DefaultClient.Do(req)
-	defer resp.Body.Close()
-
-	var tail = ".zip"
-	if strings.HasSuffix(url, "gz") {
-		tail = ".tar.gz"
+// fetchArchive 把 url 下载到缓存目录，已经缓存过时直接使用
+func fetchArchive(url, key string) (string, error) {
+	archive := filepath.Join(cachePath(), key+"-"+filepath.Base(url))
+	if pathExist(archive) {
+		color.White("use cached %s", archive)
+		return archive, nil
 	}
-
-	sp := filepath.Join(jdkPath, key)
-	if !pathExist(sp) {
-		err := os.Mkdir(sp, os.ModePerm)
-		if err != nil {
-			color.Red("create work dir err:%s", err)
-			return
-		}
+	if err := os.MkdirAll(cachePath(), os.ModePerm); err != nil {
+		return "", fmt.Errorf("create cache dir err:%s", err)
+	}
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := httpClient().Do(req)
+	if err != nil {
+		return "", withCode(exitNetwork, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", withCode(exitNetwork, fmt.Errorf("download %s fail:%s", url, resp.Status))
 	}
 
-	f, err := os.OpenFile(filepath.Join(sp, key+tail), os.O_CREATE|os.O_WRONLY, 0644)
+	// 先写入 .part，下载完整后才放入缓存
+	f, err := os.OpenFile(archive+".part", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
 	if err != nil {
-		color.Red("do write error:%s", err)
+		return "", fmt.Errorf("do write error:%s", err)
 	}
+	defer os.Remove(archive + ".part")
 	defer f.Close()
 
 	bar := progressbar.DefaultBytes(
 		resp.ContentLength,
 		"downloading ",
 	)
-	io.Copy(io.MultiWriter(f, bar), resp.Body)
-	if strings.Contains(key, "windows") {
-		color.White("download done.start unzip...")
-		unzipJDK(filepath.Join(sp, key+tail))
-	} else {
+	n, err := io.Copy(io.MultiWriter(f, bar), resp.Body)
+	if err != nil {
+		return "", withCode(exitNetwork, fmt.Errorf("download %s fail:%s", url, err))
+	}
+	if resp.ContentLength > 0 && n != resp.ContentLength {
+		return "", fmt.Errorf("download %s incomplete:%d of %d bytes", url, n, resp.ContentLength)
+	}
+	if err = f.Close(); err != nil {
+		return "", err
+	}
+	return archive, os.Rename(archive+".part", archive)
+}
+
+func downloadJdkTo(url, key string) error {
+	archive, err := fetchArchive(url, key)
+	if err != nil {
+		return err
+	}
+
+	// 先解压到隐藏的临时目录，完成后再重命名，其他进程不会看到解压了一半的jdk
+	sp := filepath.Join(jdkPath, key)
+	tmp := filepath.Join(jdkPath, "."+key+".part")
+	if err = os.RemoveAll(tmp); err != nil {
+		return err
+	}
+	if err = os.Mkdir(tmp, os.ModePerm); err != nil {
+		return fmt.Errorf("create work dir err:%s", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	if strings.HasSuffix(url, "gz") {
 		color.White("download done.start extract...")
-		extractRelevantDirs(filepath.Join(sp, key+tail))
+		err = extractRelevantDirs(archive, tmp)
+	} else {
+		color.White("download done.start unzip...")
+		err = unzipJDK(archive, tmp)
+	}
+	if err != nil {
+		// 缓存的安装包已损坏，删除后下次重新下载
+		os.Remove(archive)
+		return fmt.Errorf("extract %s fail:%s", archive, err)
+	}
+	if err = os.RemoveAll(sp); err != nil {
+		return fmt.Errorf("remove old %s fail:%s", sp, err)
+	}
+	if err = os.Rename(tmp, sp); err != nil {
+		return err
+	}
+	if err = writeManifest(key, url, archive); err != nil {
+		color.Yellow("write manifest of %s fail:%s", key, err)
 	}
+	pruneCache(int64(getIntSetting("cache-size")) << 20)
 	color.Green("install jdk success:%s", key)
+	return withStateLock(installedChanged)
+}
+
+// installJdk 安装 key 对应的jdk
+func installJdk(key string) error {
+	url, exist := jdks[key]
+	if !exist {
+		return withCode(exitUsage, fmt.Errorf("not support for %s,use [jvm ls-remote] for installable jdks", key))
+	}
+	// 同一个jdk同时只能有一个进程安装，不同的jdk可以并行安装
+	existed := pathExist(filepath.Join(jdkPath, key))
+	unlock, err := acquireLock("install-" + key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if !existed && pathExist(filepath.Join(jdkPath, key)) {
+		color.Green("%s installed by another jvm process", key)
+		return nil
+	}
+	return downloadJdkTo(mirrorURL(url), key)
+}
+
+// homeLinkPath 返回指向当前激活jdk的链接，默认在用户目录下，--system 模式下为系统目录
+func homeLinkPath() string {
+	if config.System {
+		return local.JdkHomeLinkPath
+	}
+	return filepath.Join(dataPath, "current")
+}
+
+// removeHomeLink 删除切换模式后不再使用的链接，系统目录下的链接没有权限删除时提示
+func removeHomeLink(link string) {
+	links := []string{link}
+	if link == local.JdkHomeLinkPath && runtime.GOOS == "windows" {
+		links = append(links, local.JdkExeLinkPath)
+	}
+	for _, l := range links {
+		if _, err := os.Lstat(l); err != nil {
+			continue
+		}
+		if err := os.Remove(l); err != nil {
+			color.Yellow("remove %s of the previous mode fail:%s, remove it as root or administrator", l, err)
+		}
+	}
+}
+
+// installedJdks 返回所有已安装的jdk key
+func installedJdks() []string {
+	entries, _ := os.ReadDir(jdkPath)
+	var keys []string
+	for _, e := range entries {
+		// 接管的jdk是符号链接，需要跟随链接判断
+		// 以 . 开头的是正在安装的临时目录
+		if pathExist(filepath.Join(jdkPath, e.Name(), "bin")) && strings.Count(e.Name(), "_") == 3 && !strings.HasPrefix(e.Name(), ".") {
+			keys = append(keys, strings.TrimSpace(e.Name()))
+		}
+	}
+	return keys
 }
 
 func changeEnvSymbol(key string) {
 	originalPath := filepath.Join(jdkPath, key)
-	var symlinkPath = local.JdkHomeLinkPath
+	system := config.System
+	if system {
+		if err := local.CheckSystemWritable(); err != nil {
+			failf("%s", err)
+			return
+		}
+	}
+	var symlinkPath = homeLinkPath()
 	if _, err := os.Lstat(symlinkPath); err == nil {
 		if err = os.Remove(symlinkPath); err != nil {
-			color.Red("remove old version link fail:%s", err)
+			failf("remove old version link fail:%s", err)
 			return
 		}
 	}
 	err := os.Symlink(originalPath, symlinkPath)
 	if err != nil {
-		color.Red("active new version fail:%s", err)
+		failf("active new version fail:%s", err)
 		return
 	} else {
-		if runtime.GOOS == "windows" {
+		if system && runtime.GOOS == "windows" {
 			exePath := filepath.Join(jdkPath, key, "bin")
 			var exeSymPath = local.JdkExeLinkPath
 			if _, err := os.Lstat(exeSymPath); err == nil {
 				if err = os.Remove(exeSymPath); err != nil {
-					color.Red("remove old version link fail:%s", err)
+					failf("remove old version link fail:%s", err)
 					return
 				}
 			}
 			err := os.Symlink(exePath, exeSymPath)
 			if err != nil {
-				color.Red("active new version fail:%s", err)
+				failf("active new version fail:%s", err)
 			}
 		}
 	}
@@ -483,36 +889,13 @@ func changeEnvSymbol(key string) {
 	color.Green("active %s %s success", p[0], p[1])
 	color.Green("use java --version find out")
 	color.Green("use jvm list show all installed jdks")
-	config[ckActivated] = key
+	config.Active = key
 }
 
 //====================util end==========================//
 
 //====================cmd start==========================//
 
-func help(commands []CmdInfo) {
-	color.White("Usage:")
-	color.White("")
-	var cmdLen = -1
-	for _, cmd := range commands {
-		l := len(fmt.Sprintf("  jvm %s  ", cmd.cmd))
-		if l > cmdLen {
-			cmdLen = l
-		}
-	}
-	for _, cmd := range commands {
-		c := fmt.Sprintf("  jvm %s", cmd.cmd)
-		c = c + strings.Repeat(" ", cmdLen-len(c))
-		color.New(color.FgCyan).Print(c)
-		for idx, line := range strings.Split(cmd.desc, "\n") {
-			if idx == 0 {
-				color.White(" : %s", line)
-			} else {
-				color.White(strings.Repeat(" ", cmdLen) + fmt.Sprintf("   %s", line))
-			}
-		}
-	}
-}
 func contains[T comparable](s []T, e T) bool {
 	for _, a := range s {
 		if a == e {
@@ -522,28 +905,49 @@ func contains[T comparable](s []T, e T) bool {
 	return false
 }
 
-func useJdk(subs []string) {
-	if subs == nil || len(subs) == 0 {
-		color.Yellow("version required,use [jvm help] for detail")
-		return
+// parseVersionVendor 解析 <version> [vendor] 参数，返回当前系统对应的jdk key
+func parseVersionVendor(subs []string) (string, bool) {
+	subs, ok := expandSelection(subs, installedJdks())
+	if !ok {
+		failCode(exitNotInstalled, "no installed jdk matches %s", strings.Join(subs, " "))
+		return "", false
 	}
 	version := subs[0]
 	if !contains(supportVersion, version) {
-		color.Red("un support version:%s, use [jvm detail] for help", version)
-		return
+		usagef("un support version:%s, one of %s", version, strings.Join(supportVersion, "|"))
+		return "", false
 	}
-	var vendor = "liberica"
+	var vendor = getSetting("default-vendor")
 	if len(subs) > 1 {
-		if !contains(supportVendor, subs[1]) {
-			color.Red("un support jdk type:%s, use [jvm detail] for help", subs[1])
-			return
+		if !contains(selectVendor, subs[1]) {
+			usagef("un support jdk type:%s, one of %s", subs[1], strings.Join(selectVendor, "|"))
+			return "", false
 		} else {
 			vendor = subs[1]
 		}
 	}
-	key := downloadKeyBy(version, vendor)
+	// --vendor 与 [vendor] 参数相同
+	if flagVendor != "" {
+		if len(subs) > 1 && subs[1] != flagVendor {
+			usagef("vendor %s conflicts with --vendor %s", subs[1], flagVendor)
+			return "", false
+		}
+		vendor = flagVendor
+	}
+	return downloadKeyBy(version, vendor), true
+}
+
+func useJdk(subs []string) {
+	if subs == nil || len(subs) == 0 {
+		usagef("version required,use [jvm help use] for detail")
+		return
+	}
+	key, ok := parseVersionVendor(subs)
+	if !ok {
+		return
+	}
 	if !pathExist(filepath.Join(jdkPath, key)) {
-		color.Red("current version not install try jvm inst <version> [param] first")
+		failCode(exitNotInstalled, "%s not installed, try [jvm inst %s] first", key, strings.Join(subs, " "))
 		return
 	}
 	changeEnvSymbol(key)
@@ -551,72 +955,294 @@ func useJdk(subs []string) {
 
 func instJdk(subs []string) {
 	if subs == nil || len(subs) == 0 {
-		color.Yellow("missing param:<version>")
+		usagef("missing param:<version>, use [jvm help inst] for detail")
 		return
 	}
-	version := subs[0]
-	if !contains(supportVersion, version) {
-		color.Red("un support version:%s, use [jvm detail] for help", version)
+	// 约束按可下载的jdk解析
+	subs, ok := expandSelection(subs, catalogJdks())
+	if !ok {
+		usagef("no downloadable jdk matches %s", strings.Join(subs, " "))
 		return
 	}
-	var vendor = "liberica"
-	if len(subs) > 1 {
-		if !contains(supportVendor, subs[1]) {
-			color.Red("un support jdk type:%s, use [jvm detail] for help", subs[1])
-			return
-		} else {
-			vendor = subs[1]
-		}
+	key, ok := parseVersionVendor(subs)
+	if !ok {
+		return
+	}
+	if vendor := strings.Split(key, "_")[0]; !contains(supportVendor, vendor) {
+		usagef("%s jdks are adopted by [jvm on] and can not be downloaded, one of %s", vendor, strings.Join(supportVendor, "|"))
+		return
 	}
-	key := downloadKeyBy(version, vendor)
-	fmt.Println(key)
 	url, exist := jdks[key]
 	if !exist {
-		color.Red("not support for %s,use [jvm detail] for help", key)
+		usagef("not support for %s,use [jvm ls-remote] for installable jdks", key)
+		return
+	}
+	if flagDryRun {
+		target := filepath.Join(jdkPath, key)
+		if jsonOutput() {
+			printJSON("inst", map[string]interface{}{"jdk": newJdkEntry(key), "url": mirrorURL(url), "dry_run": true})
+			return
+		}
+		color.White("would download %s", mirrorURL(url))
+		if pathExist(target) && !flagForce {
+			color.White("%s is already installed at %s, nothing to do without --force", key, target)
+			return
+		}
+		color.White("and install %s into %s", key, target)
+		return
+	}
+	if pathExist(filepath.Join(jdkPath, key)) && !flagForce {
+		if jsonOutput() {
+			m, _ := readManifest(key)
+			printJSON("inst", map[string]interface{}{"jdk": newJdkEntry(key), "manifest": m})
+			return
+		}
+		color.Yellow("%s already installed, use --force to install it again", key)
+		return
+	}
+	if !jsonOutput() {
+		fmt.Println(key)
+	}
+	if err := installJdk(key); err != nil {
+		failErr(err)
 		return
 	}
-	downloadJdkTo(url, key)
+	if jsonOutput() {
+		m, _ := readManifest(key)
+		printJSON("inst", map[string]interface{}{"jdk": newJdkEntry(key), "manifest": m})
+	}
 }
 
 func currentActiveJdk(subs []string) {
-	act := getConfig(ckActivated, "")
-	if act == "" {
-		color.Yellow("no jdk activated;use [jvm inst] to install,use [jvm use] to active")
+	if len(subs) > 0 {
+		key, ok := parseVersionVendor(subs)
+		if !ok {
+			return
+		}
+		if jsonOutput() {
+			printJSON("cur", map[string]interface{}{"jdk": newJdkEntry(key)})
+			return
+		}
+		ns := strings.Split(key, "_")
+		switch {
+		case key == config.Active:
+			color.Magenta("  %s current active", jdkLabel(key))
+		case pathExist(filepath.Join(jdkPath, key)):
+			color.Blue("  %s installed, not active", jdkLabel(key))
+		default:
+			color.Yellow("  %s [%s] not installed", ns[1], ns[0])
+		}
+		return
+	}
+	effectiveJdk()
+}
+
+// effectiveJdk 显示当前目录和shell实际生效的jdk：选择的来源，以及 JAVA_HOME 和PATH中的 java 是否与之一致
+func effectiveJdk() {
+	key, src := resolveKey()
+	if key == "" && src != "" {
+		// 项目版本文件写错了，resolveKey 已经报错
+		return
+	}
+	jh := os.Getenv("JAVA_HOME")
+	java, _ := exec.LookPath("java")
+	javaKey := ""
+	if java != "" {
+		javaKey = javaKeyOf(java)
+	}
+	homeOk := key != "" && jh != "" && samePath(jh, filepath.Join(jdkPath, key))
+	javaOk := key != "" && javaKey == key
+	if jsonOutput() {
+		var e *jdkEntry
+		if key != "" {
+			en := newJdkEntry(key)
+			e = &en
+		}
+		printJSON("cur", map[string]interface{}{"jdk": e, "source": src, "java_home": jh, "java_home_matches": homeOk, "java": java, "java_matches": javaOk})
+	}
+	if key == "" {
+		if !jsonOutput() {
+			color.Yellow("no jdk activated;use [jvm inst] to install,use [jvm use] to active")
+		}
+		return
+	}
+	ns := strings.Split(key, "_")
+	if !pathExist(filepath.Join(jdkPath, key)) {
+		failCode(exitNotInstalled, "%s from %s is not installed, use [jvm inst %s %s]", key, sourceLabel(src), ns[1], ns[0])
+		return
+	}
+	if src == "global" {
+		if _, err := filepath.EvalSymlinks(homeLinkPath()); err != nil {
+			failf("%s is broken, use [jvm use %s %s] to relink", homeLinkPath(), ns[1], ns[0])
+			return
+		}
+	}
+	if jsonOutput() {
+		return
+	}
+	field := func(name, format string, a ...interface{}) {
+		color.New(color.FgCyan).Printf("  %-10s", name)
+		color.White(format, a...)
+	}
+	color.Magenta("  %s%s", jdkLabel(key), aliasNote(key))
+	field("from", "%s", sourceLabel(src))
+	if m, ok := readManifest(key); ok {
+		field("installed", "%s by jvm %s, %s, %s", m.InstalledAt.Local().Format("2006-01-02 15:04"), m.JvmVersion, m.Package, formatSize(m.Size))
+	}
+	// describe 说明 JAVA_HOME 或 java 对应的jdk
+	describe := func(p, k, none string) string {
+		switch {
+		case p == "":
+			return none
+		case k == "":
+			return p + " (not managed by jvm)"
+		}
+		return p + " (" + jdkLabel(k) + ")"
+	}
+	if homeOk {
+		field("JAVA_HOME", "%s", jh)
 	} else {
-		ns := strings.Split(act, "_")
-		color.Magenta("  %s [%s]", ns[1], ns[0])
+		field("JAVA_HOME", "%s", color.YellowString("%s, not the selected jdk", describe(jh, jdkKeyOf(jh), "not set")))
+	}
+	if javaOk {
+		field("java", "%s", java)
+	} else {
+		field("java", "%s", color.YellowString("%s, not the selected jdk", describe(java, javaKey, "not found on PATH")))
+	}
+	if !homeOk || !javaOk {
+		color.Yellow("  this shell does not use the selected jdk, see [jvm doctor]")
 	}
 }
 
+// aliasNote 列出指向 key 的别名
+func aliasNote(key string) string {
+	if as := aliasesOf(key); len(as) > 0 {
+		return " alias " + strings.Join(as, ",")
+	}
+	return ""
+}
+
 func listInstalledJdk(subs []string) {
-	entries, err := os.ReadDir(jdkPath)
-	if err != nil {
-		color.Red("list jdks failed:%s", err)
+	if _, err := os.ReadDir(jdkPath); err != nil {
+		failf("list jdks failed:%s", err)
+		return
+	}
+	want := ""
+	if len(subs) > 0 {
+		key, ok := parseVersionVendor(subs)
+		if !ok {
+			return
+		}
+		want = key
+	}
+	act := config.Active
+	entries := []jdkEntry{}
+	for _, name := range installedJdks() {
+		if want != "" && name != want {
+			continue
+		}
+		if flagVendor != "" && !strings.HasPrefix(name, flagVendor+"_") {
+			continue
+		}
+		if jsonOutput() {
+			entries = append(entries, newJdkEntry(name))
+			continue
+		}
+		if act == name {
+			color.Magenta("  %s current active%s", jdkLabel(name), aliasNote(name))
+		} else {
+			color.Blue("  %s %s", jdkLabel(name), aliasNote(name))
+		}
+	}
+	if jsonOutput() {
+		printJSON("list", map[string]interface{}{"jdks": entries})
+	}
+}
+
+// remoteEntry ls-remote 输出中一个可以安装的jdk
+type remoteEntry struct {
+	Key       string `json:"key"`
+	Vendor    string `json:"vendor"`
+	Major     string `json:"major"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	URL       string `json:"url"`
+	Installed bool   `json:"installed"`
+}
+
+// listRemoteJdk 列出当前系统可以安装的jdk，可以按版本和厂商过滤
+func listRemoteJdk(subs []string) {
+	var version, vendor = "", flagVendor
+	for _, s := range subs {
+		if contains(supportVendor, s) {
+			vendor = s
+		} else if contains(supportVersion, s) {
+			version = s
+		} else {
+			usagef("un support version or vendor:%s", s)
+			return
+		}
+	}
+	keys := catalogJdks()
+	sort.Slice(keys, func(i, j int) bool {
+		if versionNum(keys[i]) != versionNum(keys[j]) {
+			return versionNum(keys[i]) > versionNum(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	entries := []remoteEntry{}
+	for _, k := range keys {
+		ns := strings.Split(k, "_")
+		if (version != "" && ns[1] != version) || (vendor != "" && ns[0] != vendor) {
+			continue
+		}
+		entries = append(entries, remoteEntry{Key: k, Vendor: ns[0], Major: ns[1], OS: ns[2], Arch: ns[3], URL: mirrorURL(jdks[k]), Installed: pathExist(filepath.Join(jdkPath, k))})
+	}
+	if jsonOutput() {
+		printJSON("ls-remote", map[string]interface{}{"jdks": entries})
 		return
 	}
-	act := getConfig(ckActivated, "")
 	for _, e := range entries {
-		if e.IsDir() && strings.Count(e.Name(), "_") == 3 {
-			name := strings.TrimSpace(e.Name())
-			ns := strings.Split(name, "_")
-			if act == name {
-				color.Magenta("  %s [%s] current active", ns[1], ns[0])
-			} else {
-				color.Blue("  %s [%s] ", ns[1], ns[0])
-			}
+		if e.Installed {
+			color.Magenta("  %s [%s] installed", e.Major, e.Vendor)
+		} else {
+			color.Blue("  %s [%s]", e.Major, e.Vendor)
 		}
 	}
 }
 
 func disableJvm(subs []string) {
-	config[ckEnabled] = "false"
-	color.Green("jvm enabled try [jvm inst <version> or jvm use <version>] to use")
+	local.TeardownJavaHomeAndPath(homeLinkPath(), shimsPath(), config.System)
+	config.Enabled = false
+	config.Completion = false
+	color.Green("jvm disabled, open a new terminal to use the old env")
 
 }
 
 func enableJvm(subs []string) {
-	local.SetupJavaHomeAndPath()
-	config[ckEnabled] = "true"
+	system := flagSystem
+	if system {
+		if err := local.CheckSystemWritable(); err != nil {
+			failf("%s", err)
+			return
+		}
+	}
+	old := homeLinkPath()
+	config.System = system
+	config.Completion = config.Completion || flagCompletion
+	migrateJavaSettings()
+	if _, err := rehash(); err != nil {
+		color.Red("rehash fail:%s", err)
+	}
+	local.SetupJavaHomeAndPath(homeLinkPath(), shimsPath(), system, config.Completion)
+	config.Enabled = true
+	// 切换模式后把已激活的jdk重新链接到新位置
+	if act := config.Active; act != "" && pathExist(filepath.Join(jdkPath, act)) {
+		changeEnvSymbol(act)
+	}
+	if old != homeLinkPath() {
+		removeHomeLink(old)
+	}
 	color.Green("jvm enabled try [jvm inst <version> or jvm use <version>] to use")
 }
 
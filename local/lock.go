@@ -0,0 +1,6 @@
+package local
+
+import "errors"
+
+// ErrLocked 锁已被其他进程持有
+var ErrLocked = errors.New("locked by another process")
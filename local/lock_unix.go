@@ -0,0 +1,21 @@
+//go:build !windows
+
+package local
+
+import (
+	"golang.org/x/sys/unix"
+	"os"
+)
+
+// TryLock 尝试对文件加排他锁，已被其他进程持有时返回 ErrLocked
+func TryLock(f *os.File) error {
+	err := unix.Flock(int(f.Fd()), unix.LOCK_EX|unix.LOCK_NB)
+	if err == unix.EWOULDBLOCK {
+		return ErrLocked
+	}
+	return err
+}
+
+func Unlock(f *os.File) error {
+	return unix.Flock(int(f.Fd()), unix.LOCK_UN)
+}
@@ -0,0 +1,26 @@
+//go:build windows
+
+package local
+
+import (
+	"golang.org/x/sys/windows"
+	"os"
+)
+
+// 锁住文件末尾之后的区域，不影响其他进程读取文件中的pid
+func lockRange() *windows.Overlapped {
+	return &windows.Overlapped{OffsetHigh: 1}
+}
+
+// TryLock 尝试对文件加排他锁，已被其他进程持有时返回 ErrLocked
+func TryLock(f *os.File) error {
+	err := windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, lockRange())
+	if err == windows.ERROR_LOCK_VIOLATION {
+		return ErrLocked
+	}
+	return err
+}
+
+func Unlock(f *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, lockRange())
+}
@@ -0,0 +1,215 @@
+//go:build !windows
+
+package local
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/fatih/color"
+	"golang.org/x/sys/unix"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+// CheckSystemWritable 检查是否有权限在系统目录下创建jdk链接
+func CheckSystemWritable() error {
+	if os.Geteuid() == 0 {
+		return nil
+	}
+	dir := filepath.Dir(JdkHomeLinkPath)
+	if err := unix.Access(dir, unix.W_OK); err != nil {
+		return fmt.Errorf("system mode needs write access to %s, rerun with sudo: %s", dir, err)
+	}
+	return nil
+}
+
+// LoginShell 返回用户的shell名称，优先 $SHELL，其次 /etc/passwd 中的登录shell
+func LoginShell() string {
+	if sh := os.Getenv("SHELL"); sh != "" {
+		return filepath.Base(sh)
+	}
+	if sh := passwdShell(); sh != "" {
+		return filepath.Base(sh)
+	}
+	return defaultShell
+}
+
+// passwdShell 从 /etc/passwd 中读取当前用户的登录shell
+func passwdShell() string {
+	u, err := user.Current()
+	if err != nil {
+		return ""
+	}
+	file, err := os.Open("/etc/passwd")
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fs := strings.Split(scanner.Text(), ":")
+		if len(fs) == 7 && fs[0] == u.Username {
+			return fs[6]
+		}
+	}
+	return ""
+}
+
+// ShellProfile 返回shell对应的配置文件
+func ShellProfile(shell string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	switch shell {
+	case "bash":
+		return bashProfile(home), nil
+	case "zsh":
+		if zd := os.Getenv("ZDOTDIR"); zd != "" {
+			return filepath.Join(zd, ".zshrc"), nil
+		}
+		return filepath.Join(home, ".zshrc"), nil
+	case "fish":
+		cfg := os.Getenv("XDG_CONFIG_HOME")
+		if cfg == "" {
+			cfg = filepath.Join(home, ".config")
+		}
+		return filepath.Join(cfg, "fish", "conf.d", "jvm.fish"), nil
+	default:
+		return filepath.Join(home, ".profile"), nil
+	}
+}
+
+// profileSnippet 生成设置 JAVA_HOME 和 PATH 的配置内容，shims 在jdk的bin之前，completion 时加载 jvm completion 的补全，
+// jvm shell 和 jvm env 选择的jdk会设置 JVM_VERSION，子shell重新加载配置时保留它们设置的 JAVA_HOME
+func profileSnippet(shell, home, shims string, completion bool) string {
+	if shell == "fish" {
+		s := fmt.Sprintf("if not set -q JVM_VERSION; and not set -q JVM_ACTIVE\n    set -gx JAVA_HOME %s\nend\nset -gx PATH %s $JAVA_HOME/bin $PATH\n", ShellQuote(shell, home), ShellQuote(shell, shims))
+		if completion {
+			s += "if status is-interactive; and type -q jvm\n    jvm completion fish | source\nend\n"
+		}
+		return s
+	}
+	s := fmt.Sprintf("if [ -z \"${JVM_VERSION-}\" ] && [ -z \"${JVM_ACTIVE-}\" ]; then\n    export JAVA_HOME=%s\nfi\nexport PATH=%s:\"$JAVA_HOME/bin:$PATH\"\n", ShellQuote(shell, home), ShellQuote(shell, shims))
+	// sh 没有可编程补全
+	if completion && (shell == "bash" || shell == "zsh") {
+		s += fmt.Sprintf("if [ -n \"$PS1\" ] && command -v jvm >/dev/null 2>&1; then\n    eval \"$(jvm completion %s)\"\nfi\n", shell)
+	}
+	return s
+}
+
+// legacySnippets 旧版本直接追加到配置文件中、没有标记块的配置
+func legacySnippets(shell, home string) []string {
+	var ls []string
+	for _, h := range []string{home, JdkHomeLinkPath} {
+		if shell == "fish" {
+			ls = append(ls, fmt.Sprintf("set -gx JAVA_HOME %s\nset -gx PATH $JAVA_HOME/bin $PATH\n", h))
+		} else {
+			ls = append(ls, fmt.Sprintf("export JAVA_HOME=%s\nexport PATH=$JAVA_HOME/bin:$PATH\n", h))
+		}
+	}
+	return ls
+}
+
+// SetupJavaHomeAndPath 在用户的shell配置中写入jvm配置块把 JAVA_HOME 指向 home 并把 shims 加入PATH，
+// system 模式下 home 为系统目录下的链接，completion 时配置块中同时加载shell补全
+func SetupJavaHomeAndPath(home, shims string, system, completion bool) {
+	shell := LoginShell()
+	ep, err := ShellProfile(shell)
+	if err != nil {
+		color.Red("error:not found env profile:%s", err)
+		return
+	}
+	old, err := os.ReadFile(ep)
+	if err != nil && !os.IsNotExist(err) {
+		color.Red("read env profile error:%s", err)
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(ep), 0755); err != nil {
+		color.Red("setup path error:%s", err)
+		return
+	}
+	content, err := replaceBlock(string(old), profileSnippet(shell, home, shims, completion), legacySnippets(shell, home))
+	if err != nil {
+		color.Red("setup path error:%s:%s", ep, err)
+		return
+	}
+	changed, err := rewriteFile(ep, content)
+	if err != nil {
+		color.Red("setup path error:%s", err)
+		return
+	}
+	// 子进程无法修改父shell的环境，需要用户自己重新加载
+	if changed {
+		color.White("updated %s, open a new terminal or source it to apply", ep)
+	}
+}
+
+// ProfileConfigured 返回登录shell的配置文件，以及其中是否有把 shims 加入PATH的jvm配置块
+func ProfileConfigured(home, shims string, system bool) (string, bool) {
+	ep, err := ShellProfile(LoginShell())
+	if err != nil {
+		return "", false
+	}
+	data, err := os.ReadFile(ep)
+	if err != nil {
+		return ep, false
+	}
+	s := string(data)
+	return ep, strings.Contains(s, BlockBegin) && strings.Contains(s, shims)
+}
+
+// TeardownJavaHomeAndPath 从所有shell配置中删除jvm配置块，恢复启用jvm之前的内容
+func TeardownJavaHomeAndPath(home, shims string, system bool) {
+	for _, shell := range []string{"bash", "zsh", "fish", "sh"} {
+		ep, err := ShellProfile(shell)
+		if err != nil {
+			color.Red("error:not found env profile:%s", err)
+			return
+		}
+		var eps = []string{ep}
+		if shell == "bash" {
+			hd, _ := os.UserHomeDir()
+			eps = []string{filepath.Join(hd, ".bashrc"), filepath.Join(hd, ".bash_profile")}
+		}
+		for _, p := range eps {
+			old, err := os.ReadFile(p)
+			if err != nil {
+				continue
+			}
+			content, err := removeBlock(string(old), legacySnippets(shell, home))
+			if err != nil {
+				color.Red("revert %s error:%s", p, err)
+				continue
+			}
+			if content == strings.TrimRight(string(old), "\n") {
+				continue
+			}
+			if content != "" {
+				content += "\n"
+			}
+			if shell == "fish" && strings.TrimSpace(content) == "" {
+				// conf.d/jvm.fish 完全由jvm生成，直接删除
+				if err = os.Remove(p); err != nil {
+					color.Red("remove %s error:%s", p, err)
+				}
+				continue
+			}
+			changed, err := rewriteFile(p, content)
+			if err != nil {
+				color.Red("revert %s error:%s", p, err)
+				continue
+			}
+			if changed {
+				color.White("reverted %s", p)
+			}
+		}
+	}
+}
+
+// Exec 用 path 替换当前进程，成功时不会返回
+func Exec(path string, args []string, env []string) error {
+	return unix.Exec(path, append([]string{path}, args...), env)
+}
@@ -3,51 +3,160 @@
 package local
 
 import (
+	"fmt"
 	"github.com/fatih/color"
 	"golang.org/x/sys/windows/registry"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 )
 
+// JdkHomeLinkPath 仅用于 --system 模式，默认使用用户目录下的 current 链接
 const JdkHomeLinkPath = "C:\\Program Files\\jvmdkhome"
 const JdkExeLinkPath = "C:\\Program Files\\jvmdkhome\\bin"
 
-func SetupJavaHomeAndPath() {
-	k, err := registry.OpenKey(registry.LOCAL_MACHINE, `SYSTEM\CurrentControlSet\Control\Session Manager\Environment`, registry.QUERY_VALUE|registry.SET_VALUE)
+const systemEnvKey = `SYSTEM\CurrentControlSet\Control\Session Manager\Environment`
+const userEnvKey = `Environment`
+
+// CheckSystemWritable 检查是否有权限修改系统级的环境变量和链接目录
+func CheckSystemWritable() error {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, systemEnvKey, registry.SET_VALUE)
+	if err != nil {
+		return fmt.Errorf("system mode requires an administrator prompt: %s", err)
+	}
+	k.Close()
+	return nil
+}
+
+// LoginShell windows下只有在git bash等环境中才会设置 $SHELL
+func LoginShell() string {
+	return strings.TrimSuffix(filepath.Base(os.Getenv("SHELL")), ".exe")
+}
+
+// SetupJavaHomeAndPath 把 JAVA_HOME 指向 home 并把 shims 和jdk的bin加入Path，system 为 true 时修改系统变量，否则只修改当前用户，
+// windows 下没有需要加载的shell补全，忽略 completion
+func SetupJavaHomeAndPath(home, shims string, system, completion bool) {
+	root, path := registry.CURRENT_USER, userEnvKey
+	if system {
+		root, path = registry.LOCAL_MACHINE, systemEnvKey
+	}
+	k, err := registry.OpenKey(root, path, registry.QUERY_VALUE|registry.SET_VALUE)
 	if err != nil {
 		color.Red("read reg err:%s", err)
 		return
 	}
 	defer k.Close()
 
-	// 读取旧的Path值
+	// 读取旧的 JAVA_HOME 值，已经指向 home 时不作操作
 	oldPath, _, err := k.GetStringValue("JAVA_HOME")
+	if oldPath != home {
+		err = k.SetStringValue("JAVA_HOME", home)
+		if err != nil {
+			color.Red("read jh err:%s", err)
+			return
+		}
+	}
 
-	// 如果旧的Path中已经包含了新目录，则不作操作
-	if strings.Contains(oldPath, JdkHomeLinkPath) {
+	// 读取旧的Path值，用户级的Path可能不存在
+	po, _, err := k.GetStringValue("Path")
+	if err != nil && err != registry.ErrNotExist {
+		color.Red("read path err:%s", err)
 		return
 	}
-	err = k.SetStringValue("JAVA_HOME", JdkHomeLinkPath)
+
+	np := po
+	for _, p := range []string{exePath(home, system), shims} {
+		// 如果旧的Path中已经包含了新目录，则不作操作
+		if !strings.Contains(np, p+";") {
+			np = p + ";" + np
+		}
+	}
+	if np == po {
+		return
+	}
+	err = k.SetStringValue("Path", np)
 	if err != nil {
-		color.Red("read jh err:%s", err)
+		color.Red("set path err:%s", err)
 		return
 	}
 
-	// 读取旧的Path值
-	po, _, err := k.GetStringValue("Path")
+}
+
+// exePath 返回加入Path的jdk bin目录，system 模式下使用单独的链接
+func exePath(home string, system bool) string {
+	if system {
+		return JdkExeLinkPath
+	}
+	return filepath.Join(home, "bin")
+}
+
+// ProfileConfigured 返回保存环境变量的注册表位置，以及其中 JAVA_HOME 和Path是否已由jvm设置
+func ProfileConfigured(home, shims string, system bool) (string, bool) {
+	root, path, where := registry.CURRENT_USER, userEnvKey, `HKCU\`+userEnvKey
+	if system {
+		root, path, where = registry.LOCAL_MACHINE, systemEnvKey, `HKLM\`+systemEnvKey
+	}
+	k, err := registry.OpenKey(root, path, registry.QUERY_VALUE)
 	if err != nil {
-		color.Red("read path err:%s", err)
-		return
+		return where, false
 	}
+	defer k.Close()
+	jh, _, _ := k.GetStringValue("JAVA_HOME")
+	po, _, _ := k.GetStringValue("Path")
+	return where, jh == home && strings.Contains(po, shims)
+}
 
-	// 如果旧的Path中已经包含了新目录，则不作操作
-	if strings.Contains(po, "%JAVA_HOME%\\bin;") {
+// TeardownJavaHomeAndPath 删除jvm设置的 JAVA_HOME 和Path中的jdk及shims目录
+func TeardownJavaHomeAndPath(home, shims string, system bool) {
+	root, path := registry.CURRENT_USER, userEnvKey
+	if system {
+		root, path = registry.LOCAL_MACHINE, systemEnvKey
+	}
+	k, err := registry.OpenKey(root, path, registry.QUERY_VALUE|registry.SET_VALUE)
+	if err != nil {
+		color.Red("read reg err:%s", err)
 		return
 	}
-	po = JdkExeLinkPath + ";" + po
-	err = k.SetStringValue("Path", po)
+	defer k.Close()
+
+	jh, _, err := k.GetStringValue("JAVA_HOME")
+	if err == nil && jh == home {
+		if err = k.DeleteValue("JAVA_HOME"); err != nil {
+			color.Red("remove jh err:%s", err)
+		}
+	}
+
+	po, _, err := k.GetStringValue("Path")
 	if err != nil {
-		color.Red("set path err:%s", err)
 		return
 	}
+	np := po
+	for _, p := range []string{exePath(home, system), shims} {
+		np = strings.ReplaceAll(np, p+";", "")
+	}
+	if np == po {
+		return
+	}
+	if err = k.SetStringValue("Path", np); err != nil {
+		color.Red("set path err:%s", err)
+	}
+}
 
+// Exec windows下无法替换当前进程，以子进程执行并以其退出码退出
+func Exec(path string, args []string, env []string) error {
+	cmd := exec.Command(path, args...)
+	cmd.Env = env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	if ee, ok := err.(*exec.ExitError); ok {
+		os.Exit(ee.ExitCode())
+	}
+	if err != nil {
+		return err
+	}
+	os.Exit(0)
+	return nil
 }
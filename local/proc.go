@@ -0,0 +1,21 @@
+package local
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Process 正在运行的进程
+type Process struct {
+	Pid int    `json:"pid"`
+	Exe string `json:"exe"`
+}
+
+// under 判断可执行文件是否位于 dir 目录下
+func under(exe, dir string) bool {
+	if runtime.GOOS == "windows" {
+		exe, dir = strings.ToLower(exe), strings.ToLower(dir)
+	}
+	return strings.HasPrefix(filepath.Clean(exe), filepath.Clean(dir)+string(filepath.Separator))
+}
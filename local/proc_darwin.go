@@ -0,0 +1,28 @@
+package local
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ProcessesUnder 返回可执行文件位于 dir 下的进程
+func ProcessesUnder(dir string) ([]Process, error) {
+	out, err := exec.Command("ps", "-axo", "pid=,comm=").Output()
+	if err != nil {
+		return nil, err
+	}
+	var ps []Process
+	for _, line := range strings.Split(string(out), "\n") {
+		fs := strings.SplitN(strings.TrimSpace(line), " ", 2)
+		if len(fs) != 2 {
+			continue
+		}
+		pid, err := strconv.Atoi(fs[0])
+		exe := strings.TrimSpace(fs[1])
+		if err == nil && under(exe, dir) {
+			ps = append(ps, Process{Pid: pid, Exe: exe})
+		}
+	}
+	return ps, nil
+}
@@ -0,0 +1,27 @@
+package local
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// ProcessesUnder 返回可执行文件位于 dir 下的进程，没有权限读取的进程被忽略
+func ProcessesUnder(dir string) ([]Process, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	var ps []Process
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		exe, err := os.Readlink(filepath.Join("/proc", e.Name(), "exe"))
+		if err == nil && under(exe, dir) {
+			ps = append(ps, Process{Pid: pid, Exe: exe})
+		}
+	}
+	return ps, nil
+}
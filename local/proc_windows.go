@@ -0,0 +1,37 @@
+//go:build windows
+
+package local
+
+import (
+	"golang.org/x/sys/windows"
+	"unsafe"
+)
+
+// ProcessesUnder 返回可执行文件位于 dir 下的进程，没有权限读取的进程被忽略
+func ProcessesUnder(dir string) ([]Process, error) {
+	snap, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(snap)
+	var pe windows.ProcessEntry32
+	pe.Size = uint32(unsafe.Sizeof(pe))
+	var ps []Process
+	for err = windows.Process32First(snap, &pe); err == nil; err = windows.Process32Next(snap, &pe) {
+		h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pe.ProcessID)
+		if err != nil {
+			continue
+		}
+		buf := make([]uint16, windows.MAX_LONG_PATH)
+		n := uint32(len(buf))
+		err = windows.QueryFullProcessImageName(h, 0, &buf[0], &n)
+		windows.CloseHandle(h)
+		if err != nil {
+			continue
+		}
+		if exe := windows.UTF16ToString(buf[:n]); under(exe, dir) {
+			ps = append(ps, Process{Pid: int(pe.ProcessID), Exe: exe})
+		}
+	}
+	return ps, nil
+}
@@ -0,0 +1,146 @@
+package local
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"os"
+	"strings"
+	"time"
+)
+
+const BlockBegin = "# >>> jvm >>>"
+const BlockEnd = "# <<< jvm <<<"
+
+// ShellQuote 按shell语法给值加单引号，profile 中的配置和 jvm env 的输出共用，路径中可能有空格
+func ShellQuote(shell, v string) string {
+	if shell == "fish" {
+		v = strings.ReplaceAll(v, `\`, `\\`)
+		return "'" + strings.ReplaceAll(v, "'", `\'`) + "'"
+	}
+	return "'" + strings.ReplaceAll(v, "'", `'\''`) + "'"
+}
+
+// BackupFile 把文件复制一份带时间戳的备份，同一秒内多次备份时加序号，文件不存在时不做任何操作
+func BackupFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	base := fmt.Sprintf("%s.jvm-backup-%s", path, time.Now().Format("20060102-150405"))
+	for i := 0; ; i++ {
+		bak := base
+		if i > 0 {
+			bak = fmt.Sprintf("%s.%d", base, i)
+		}
+		f, err := os.OpenFile(bak, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		_, err = f.Write(data)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return bak, err
+	}
+}
+
+// findBlock 返回第一个jvm配置块开始和结束的行号，没有配置块时返回 -1，有开始没有结束时返回错误
+func findBlock(lines []string) (int, int, error) {
+	begin := -1
+	for i, line := range lines {
+		switch strings.TrimSpace(line) {
+		case BlockBegin:
+			if begin < 0 {
+				begin = i
+			}
+		case BlockEnd:
+			if begin >= 0 {
+				return begin, i, nil
+			}
+		}
+	}
+	if begin >= 0 {
+		return -1, -1, fmt.Errorf("%s at line %d has no matching %s, fix it by hand", BlockBegin, begin+1, BlockEnd)
+	}
+	return -1, -1, nil
+}
+
+// removeBlock 删除内容中由 BlockBegin/BlockEnd 包围的部分以及 legacy 中的旧版配置，
+// 配置块没有结束标记时不做修改并返回错误，避免删掉其后用户自己的配置
+func removeBlock(content string, legacy []string) (string, error) {
+	for _, l := range legacy {
+		content = strings.ReplaceAll(content, l, "")
+	}
+	lines := strings.Split(content, "\n")
+	for {
+		begin, end, err := findBlock(lines)
+		if err != nil {
+			return "", err
+		}
+		if begin < 0 {
+			break
+		}
+		lines = append(lines[:begin], lines[end+1:]...)
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n"), nil
+}
+
+// replaceBlock 用 body 原地替换内容中已有的jvm配置块，没有时追加到末尾
+func replaceBlock(content, body string, legacy []string) (string, error) {
+	block := BlockBegin + "\n" + strings.TrimRight(body, "\n") + "\n" + BlockEnd
+	for _, l := range legacy {
+		content = strings.ReplaceAll(content, l, "")
+	}
+	lines := strings.Split(content, "\n")
+	begin, end, err := findBlock(lines)
+	if err != nil {
+		return "", err
+	}
+	if begin < 0 {
+		rest := strings.TrimRight(content, "\n")
+		if rest == "" {
+			return block + "\n", nil
+		}
+		return rest + "\n\n" + block + "\n", nil
+	}
+	// 之后重复的配置块删除
+	after, err := removeBlock(strings.Join(lines[end+1:], "\n"), nil)
+	if err != nil {
+		return "", err
+	}
+	out := append(lines[:begin:begin], block, after)
+	return strings.TrimRight(strings.Join(out, "\n"), "\n") + "\n", nil
+}
+
+// rewriteFile 在内容有变化时先备份再原地写入，保留符号链接指向的原文件和文件权限
+func rewriteFile(path, content string) (bool, error) {
+	old, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	if string(old) == content {
+		return false, nil
+	}
+	bak, err := BackupFile(path)
+	if err != nil {
+		return false, err
+	}
+	if bak != "" {
+		color.White("backup %s to %s", path, bak)
+	}
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	return true, os.WriteFile(path, []byte(content), perm)
+}
@@ -0,0 +1,120 @@
+package local
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const legacy = "export JAVA_HOME=/usr/local/jvmjdkhome\nexport PATH=$JAVA_HOME/bin:$PATH\n"
+
+func TestRemoveBlock(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{"no block", "alias ll='ls -l'\nexport EDITOR=vim\n", "alias ll='ls -l'\nexport EDITOR=vim", false},
+		{"empty", "", "", false},
+		{"existing block", "alias ll='ls -l'\n# >>> jvm >>>\nexport JAVA_HOME=/a\n# <<< jvm <<<\nexport EDITOR=vim\n", "alias ll='ls -l'\nexport EDITOR=vim", false},
+		{"indented markers", "a\n  # >>> jvm >>>\nx\n  # <<< jvm <<<\n", "a", false},
+		{"two blocks", "# >>> jvm >>>\nx\n# <<< jvm <<<\na\n# >>> jvm >>>\ny\n# <<< jvm <<<\n", "a", false},
+		{"unterminated block", "a\n# >>> jvm >>>\nexport JAVA_HOME=/a\nexport EDITOR=vim\n", "", true},
+		{"end without begin", "a\n# <<< jvm <<<\nb\n", "a\n# <<< jvm <<<\nb", false},
+		{"legacy snippet", "a\n" + legacy + "b\n", "a\nb", false},
+		{"legacy and block", "a\n" + legacy + "# >>> jvm >>>\nx\n# <<< jvm <<<\n", "a", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := removeBlock(tt.content, []string{legacy})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplaceBlock(t *testing.T) {
+	const body = "export JAVA_HOME=/new\n"
+	const block = "# >>> jvm >>>\nexport JAVA_HOME=/new\n# <<< jvm <<<\n"
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{"empty", "", block, false},
+		{"no block", "a\nb\n", "a\nb\n\n" + block, false},
+		{"no block without newline", "a", "a\n\n" + block, false},
+		{"existing block kept in place", "a\n# >>> jvm >>>\nexport JAVA_HOME=/old\n# <<< jvm <<<\nb\n", "a\n" + block + "b\n", false},
+		{"existing block at end", "a\n\n# >>> jvm >>>\nexport JAVA_HOME=/old\n# <<< jvm <<<\n", "a\n\n" + block, false},
+		{"duplicate block removed", "# >>> jvm >>>\nx\n# <<< jvm <<<\na\n# >>> jvm >>>\ny\n# <<< jvm <<<\nb\n", block + "a\nb\n", false},
+		{"unterminated block", "a\n# >>> jvm >>>\nb\n", "", true},
+		{"legacy snippet", "a\n" + legacy + "b\n", "a\nb\n\n" + block, false},
+		{"legacy and block", "a\n" + legacy + "# >>> jvm >>>\nx\n# <<< jvm <<<\nb\n", "a\n" + block + "b\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := replaceBlock(tt.content, body, []string{legacy})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackupFileUnique(t *testing.T) {
+	p := filepath.Join(t.TempDir(), ".bashrc")
+	if err := os.WriteFile(p, []byte("a\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		bak, err := BackupFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[bak] {
+			t.Fatalf("backup %s written twice", bak)
+		}
+		seen[bak] = true
+	}
+}
+
+func TestRewriteFileKeepsMode(t *testing.T) {
+	p := filepath.Join(t.TempDir(), ".bashrc")
+	if err := os.WriteFile(p, []byte("a\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rewriteFile(p, "b\n"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct{ shell, in, want string }{
+		{"bash", "/home/a b/jdk", `'/home/a b/jdk'`},
+		{"sh", "/home/it's/jdk", `'/home/it'\''s/jdk'`},
+		{"fish", "/home/it's/jdk", `'/home/it\'s/jdk'`},
+		{"fish", `C:\jdk`, `'C:\\jdk'`},
+	}
+	for _, tt := range tests {
+		if got := ShellQuote(tt.shell, tt.in); got != tt.want {
+			t.Errorf("ShellQuote(%s, %q) = %s, want %s", tt.shell, tt.in, got, tt.want)
+		}
+	}
+}
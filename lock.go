@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"github.com/dtdyq/jvm/local"
+	"github.com/fatih/color"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// heldLocks 当前进程持有的锁，同一进程内重复获取直接返回
+var heldLocks = map[string]*os.File{}
+
+func lockPath(name string) string {
+	return filepath.Join(workPath, "locks", name+".lock")
+}
+
+// lockHolder 读取锁文件中持有者的pid
+func lockHolder(name string) string {
+	data, _ := os.ReadFile(lockPath(name))
+	if pid := strings.TrimSpace(string(data)); pid != "" {
+		return "PID " + pid
+	}
+	return "another process"
+}
+
+// acquireLock 获取名为 name 的跨进程锁，最多等待 lock-timeout 秒，返回释放锁的函数
+func acquireLock(name string) (func(), error) {
+	if _, ok := heldLocks[name]; ok {
+		return func() {}, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(lockPath(name)), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("create lock dir err:%s", err)
+	}
+	f, err := os.OpenFile(lockPath(name), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	timeout := time.Duration(getIntSetting("lock-timeout")) * time.Second
+	deadline := time.Now().Add(timeout)
+	waiting := false
+	for {
+		err = local.TryLock(f)
+		if err == nil {
+			break
+		}
+		if err != local.ErrLocked {
+			f.Close()
+			return nil, fmt.Errorf("lock %s fail:%s", lockPath(name), err)
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("gave up after %s waiting for lock held by %s(%s)", timeout, lockHolder(name), name)
+		}
+		if !waiting {
+			color.New(color.FgYellow).Fprintf(os.Stderr, "waiting for lock held by %s(%s)...\n", lockHolder(name), name)
+			waiting = true
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	return holdLock(name, f), nil
+}
+
+// tryLock 不等待地获取名为 name 的锁，被其他进程持有时返回 false
+func tryLock(name string) (func(), bool) {
+	if _, ok := heldLocks[name]; ok {
+		return func() {}, true
+	}
+	if err := os.MkdirAll(filepath.Dir(lockPath(name)), os.ModePerm); err != nil {
+		return nil, false
+	}
+	f, err := os.OpenFile(lockPath(name), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, false
+	}
+	if local.TryLock(f) != nil {
+		f.Close()
+		return nil, false
+	}
+	return holdLock(name, f), true
+}
+
+// holdLock 记录已获取的锁和持有者的pid，返回释放锁的函数
+func holdLock(name string, f *os.File) func() {
+	f.Truncate(0)
+	f.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	heldLocks[name] = f
+	return func() {
+		releaseLock(name)
+	}
+}
+
+func releaseLock(name string) {
+	f, ok := heldLocks[name]
+	if !ok {
+		return
+	}
+	delete(heldLocks, name)
+	f.Truncate(0)
+	local.Unlock(f)
+	f.Close()
+}
+
+// releaseLocks 释放当前进程持有的所有锁
+func releaseLocks() {
+	for name := range heldLocks {
+		releaseLock(name)
+	}
+}
+
+// withStateLock 持有状态锁执行 fn，执行前重新读取配置，执行后保存，避免覆盖其他进程的修改
+func withStateLock(fn func()) error {
+	if _, ok := heldLocks["state"]; ok {
+		fn()
+		return nil
+	}
+	unlock, err := acquireLock("state")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err = loadConfig(); err != nil {
+		return err
+	}
+	fn()
+	return saveConfig()
+}
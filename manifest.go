@@ -0,0 +1,157 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// jvmVersion 发布时通过 -ldflags "-X main.jvmVersion=<version>" 设置
+var jvmVersion = "dev"
+
+// manifestVersion 当前安装清单的结构版本
+const manifestVersion = 1
+
+// Manifest 安装时记录在jdk目录旁的 <key>.json 中的安装信息
+type Manifest struct {
+	Version     int       `json:"version"`
+	Key         string    `json:"key"`
+	Vendor      string    `json:"vendor"`
+	JavaVersion string    `json:"java_version"`
+	OS          string    `json:"os"`
+	Arch        string    `json:"arch"`
+	URL         string    `json:"url,omitempty"`
+	SHA256      string    `json:"sha256,omitempty"`
+	InstalledAt time.Time `json:"installed_at"`
+	Size        int64     `json:"size"`
+	// Package 安装包类型 tar.gz、zip，接管的jdk为 adopted
+	Package string `json:"package"`
+	// Source 接管的jdk的原目录
+	Source     string `json:"source,omitempty"`
+	JvmVersion string `json:"jvm_version"`
+}
+
+func manifestPath(key string) string {
+	return filepath.Join(jdkPath, key+".json")
+}
+
+// readManifest 读取 key 的安装清单，旧版本安装的jdk没有清单
+func readManifest(key string) (Manifest, bool) {
+	var m Manifest
+	data, err := os.ReadFile(manifestPath(key))
+	if err != nil {
+		return m, false
+	}
+	if err = json.Unmarshal(data, &m); err != nil {
+		return m, false
+	}
+	return m, true
+}
+
+// installedVersion 返回安装清单或jdk release 文件中的完整版本
+func installedVersion(key string) (string, bool) {
+	if m, ok := readManifest(key); ok && m.JavaVersion != "" {
+		return m.JavaVersion, true
+	}
+	if v := readRelease(filepath.Join(jdkPath, key))["JAVA_VERSION"]; v != "" {
+		return v, true
+	}
+	return "", false
+}
+
+// jdkFullVersion 返回jdk的完整版本，读取不到时返回主版本
+func jdkFullVersion(key string) string {
+	if v, ok := installedVersion(key); ok {
+		return v
+	}
+	return strings.Split(key, "_")[1]
+}
+
+// writeManifest 根据安装好的jdk目录生成安装清单，archive 为空表示接管的jdk
+func writeManifest(key, url, archive string) error {
+	ns := strings.Split(key, "_")
+	home := filepath.Join(jdkPath, key)
+	m := Manifest{
+		Version:     manifestVersion,
+		Key:         key,
+		Vendor:      ns[0],
+		JavaVersion: readRelease(home)["JAVA_VERSION"],
+		OS:          ns[2],
+		Arch:        ns[3],
+		URL:         url,
+		InstalledAt: time.Now().UTC().Truncate(time.Second),
+		Size:        dirSize(home),
+		Package:     "adopted",
+		JvmVersion:  jvmVersion,
+	}
+	if archive != "" {
+		sum, err := fileSha256(archive)
+		if err != nil {
+			return err
+		}
+		m.SHA256 = sum
+		m.Package = "zip"
+		if strings.HasSuffix(url, "gz") {
+			m.Package = "tar.gz"
+		}
+	} else if src, err := filepath.EvalSymlinks(home); err == nil {
+		m.Source = src
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(manifestPath(key), append(data, '\n'), 0644)
+}
+
+func fileSha256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// dirSize 目录下所有文件的大小，跟随目录本身的符号链接
+func dirSize(dir string) int64 {
+	if p, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = p
+	}
+	var n int64
+	filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			n += info.Size()
+		}
+		return nil
+	})
+	return n
+}
+
+func formatSize(n int64) string {
+	return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+}
+
+// jdkLabel 显示用的 <major> [vendor]，有清单时带上完整版本
+func jdkLabel(key string) string {
+	ns := strings.Split(key, "_")
+	label := fmt.Sprintf("%s [%s]", ns[1], ns[0])
+	if m, ok := readManifest(key); ok && m.JavaVersion != "" {
+		label += " " + m.JavaVersion
+	}
+	// 通过 --arch 安装的其他架构的jdk
+	if !strings.HasSuffix(key, downloadKeyBy("", "")[1:]) {
+		label += " (" + ns[2] + "/" + ns[3] + ")"
+	}
+	return label
+}
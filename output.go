@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/fatih/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// schemaVersion --json 输出的结构版本，只会增加字段，有不兼容的修改时递增
+const schemaVersion = 1
+
+// outputFormat 输出格式 text|json，由全局参数 --json 或 --format 指定
+var outputFormat = "text"
+
+// cmdErr 命令执行中出现的错误，json 模式下输出为 error
+var cmdErr error
+
+// jsonPrinted 命令已经输出了json结果
+var jsonPrinted = false
+
+// jsonResult --json 输出的外层结构，data 与 error 只会有一个
+type jsonResult struct {
+	Schema int         `json:"schema"`
+	Kind   string      `json:"kind"`
+	Data   interface{} `json:"data,omitempty"`
+	Error  *jsonError  `json:"error,omitempty"`
+}
+
+type jsonError struct {
+	Message string `json:"message"`
+	// Code 进程的退出码，Type 为其含义 failure|usage|network|verification|not-installed
+	Code int    `json:"code"`
+	Type string `json:"type"`
+}
+
+// jdkEntry list、cur、inst 等输出中的一个jdk
+type jdkEntry struct {
+	Key         string     `json:"key"`
+	Vendor      string     `json:"vendor"`
+	Major       string     `json:"major"`
+	JavaVersion string     `json:"java_version,omitempty"`
+	Path        string     `json:"path"`
+	Installed   bool       `json:"installed"`
+	Active      bool       `json:"active"`
+	Aliases     []string   `json:"aliases"`
+	InstalledAt *time.Time `json:"installed_at,omitempty"`
+}
+
+func jsonOutput() bool {
+	return outputFormat == "json"
+}
+
+// parseGlobalFlags 取出可以出现在 -- 之前任意位置的 --json、--format <text|json>
+func parseGlobalFlags(args []string) ([]string, error) {
+	var tail []string
+	for i, a := range args {
+		if a == "--" {
+			args, tail = args[:i], args[i:]
+			break
+		}
+	}
+	format, rest := popFlag(args, "--format")
+	var ret []string
+	for _, a := range rest {
+		if a == "--json" {
+			format = "json"
+			continue
+		}
+		ret = append(ret, a)
+	}
+	ret = append(ret, tail...)
+	switch format {
+	case "":
+	case "text", "json":
+		outputFormat = format
+	default:
+		return ret, fmt.Errorf("un support format:%s, use text or json", format)
+	}
+	if jsonOutput() {
+		// 提示信息输出到stderr，stdout只输出json
+		color.Output = color.Error
+	}
+	return ret, nil
+}
+
+// printJSON 输出命令结果
+func printJSON(kind string, data interface{}) {
+	writeJSON(jsonResult{Schema: schemaVersion, Kind: kind, Data: data})
+}
+
+// printJSONError 命令没有输出结果时输出错误
+func printJSONError(err error) {
+	if jsonPrinted {
+		return
+	}
+	writeJSON(jsonResult{Schema: schemaVersion, Kind: "error", Error: &jsonError{Message: err.Error(), Code: exitCode, Type: exitNames[exitCode]}})
+}
+
+func writeJSON(r jsonResult) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		color.Red("%s", err)
+		return
+	}
+	fmt.Fprintln(os.Stdout, string(data))
+	jsonPrinted = true
+}
+
+func newJdkEntry(key string) jdkEntry {
+	ns := strings.Split(key, "_")
+	e := jdkEntry{
+		Key:       key,
+		Vendor:    ns[0],
+		Major:     ns[1],
+		Path:      filepath.Join(jdkPath, key),
+		Installed: pathExist(filepath.Join(jdkPath, key)),
+		Active:    key == config.Active,
+		Aliases:   aliasesOf(key),
+	}
+	if e.Aliases == nil {
+		e.Aliases = []string{}
+	}
+	// 读取不到完整版本时留空，不用主版本冒充
+	e.JavaVersion, _ = installedVersion(key)
+	if m, ok := readManifest(key); ok {
+		e.InstalledAt = &m.InstalledAt
+	}
+	return e
+}
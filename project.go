@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"github.com/fatih/color"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// projectFile jvm自己的项目版本文件，内容为 <version> [vendor]
+const projectFile = ".jvm-version"
+
+// versionFiles 按优先级排列的项目版本文件
+var versionFiles = []string{projectFile, ".java-version", ".sdkmanrc", ".tool-versions"}
+
+// vendorAliases 其他工具中的厂商名称与jvm厂商的对应关系
+var vendorAliases = map[string]string{
+	"liberica": "liberica",
+	"librca":   "liberica",
+	"openjdk":  "openjdk",
+	"open":     "openjdk",
+	"oracle":   "oracle",
+	"graal":    "graal",
+	"graalce":  "graal",
+	"graalvm":  "graal",
+	"local":    "local",
+}
+
+// splitVendorVersion 解析 liberica64-17.0.10、17.0.10-librca、openjdk-17 这类写法，返回 <version> [vendor]，
+// 不认识的厂商原样返回，由 parseVersionVendor 报错，不能当作默认厂商
+func splitVendorVersion(s string) []string {
+	var version, vendor string
+	for _, p := range strings.Split(strings.TrimSpace(s), "-") {
+		if p == "" {
+			continue
+		}
+		if p[0] >= '0' && p[0] <= '9' {
+			if version == "" {
+				version = majorVersion(p)
+			}
+			continue
+		}
+		if vendor != "" {
+			continue
+		}
+		// jenv 的厂商名称带有位数，如 liberica64
+		if v, ok := vendorAliases[strings.TrimRight(strings.ToLower(p), "0123456789")]; ok {
+			vendor = v
+		} else {
+			vendor = p
+		}
+	}
+	if version == "" {
+		return nil
+	}
+	if vendor == "" {
+		return []string{version}
+	}
+	return []string{version, vendor}
+}
+
+// parseVersionFile 从版本文件中读取 <version> [vendor]
+func parseVersionFile(path string) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	name := filepath.Base(path)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		switch name {
+		case projectFile:
+			return strings.Fields(line)
+		case ".java-version":
+			return splitVendorVersion(line)
+		case ".sdkmanrc":
+			if kv := strings.SplitN(line, "=", 2); len(kv) == 2 && strings.TrimSpace(kv[0]) == "java" {
+				return splitVendorVersion(kv[1])
+			}
+		case ".tool-versions":
+			if fs := strings.Fields(line); len(fs) > 1 && fs[0] == "java" {
+				return splitVendorVersion(fs[1])
+			}
+		}
+	}
+	return nil
+}
+
+// findProjectVersion 从 dir 开始向上查找项目版本文件，返回其中的 <version> [vendor] 和文件路径
+func findProjectVersion(dir string) ([]string, string) {
+	for {
+		for _, n := range versionFiles {
+			p := filepath.Join(dir, n)
+			if !pathExist(p) {
+				continue
+			}
+			if subs := parseVersionFile(p); len(subs) > 0 {
+				return subs, p
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, ""
+		}
+		dir = parent
+	}
+}
+
+// resolveKey 按 JVM_VERSION > 项目版本文件 > 全局激活 的顺序确定应使用的jdk及其来源，不检查是否已安装
+func resolveKey() (string, string) {
+	if v := os.Getenv("JVM_VERSION"); v != "" {
+		key, _ := parseVersionVendor(strings.Fields(v))
+		return key, "JVM_VERSION"
+	}
+	wd, err := os.Getwd()
+	if err == nil {
+		if subs, file := findProjectVersion(wd); file != "" {
+			key, ok := parseVersionVendor(subs)
+			if !ok {
+				color.Yellow("%s wants %s, fix it or use [jvm local <version> [vendor]]", file, strings.Join(subs, " "))
+			}
+			return key, file
+		}
+	}
+	if config.Active != "" {
+		return config.Active, "global"
+	}
+	return "", ""
+}
+
+// resolveHome 返回应使用的jdk目录及其来源，全局激活的jdk使用链接，未安装时给出提示
+func resolveHome() (string, string) {
+	key, source := resolveKey()
+	switch {
+	case key == "":
+		return "", ""
+	case source == "global":
+		return homeLinkPath(), source
+	case !pathExist(filepath.Join(jdkPath, key)):
+		ns := strings.Split(key, "_")
+		color.Yellow("%s wants %s which is not installed, use [jvm inst %s %s] first", source, key, ns[1], ns[0])
+		return "", ""
+	}
+	return filepath.Join(jdkPath, key), source
+}
+
+// sourceLabel 显示用的jdk来源
+func sourceLabel(source string) string {
+	if source == "global" {
+		return "the global selection [jvm use]"
+	}
+	return source
+}
+
+// localJdk 在当前目录写入项目版本文件，不带参数时显示当前目录生效的项目版本
+func localJdk(subs []string) {
+	wd, err := os.Getwd()
+	if err != nil {
+		failf("%s", err)
+		return
+	}
+	if len(subs) == 0 {
+		vs, file := findProjectVersion(wd)
+		if file == "" {
+			color.Yellow("no project version file found, use [jvm local <version> [vendor]] to create one")
+			return
+		}
+		color.Magenta("  %s (%s)", strings.Join(vs, " "), file)
+		return
+	}
+	key, ok := parseVersionVendor(subs)
+	if !ok {
+		return
+	}
+	ns := strings.Split(key, "_")
+	if err = os.WriteFile(filepath.Join(wd, projectFile), []byte(ns[1]+" "+ns[0]+"\n"), 0644); err != nil {
+		failf("write %s fail:%s", projectFile, err)
+		return
+	}
+	color.Green("%s %s [%s] written", filepath.Join(wd, projectFile), ns[1], ns[0])
+	if !pathExist(filepath.Join(jdkPath, key)) {
+		color.Yellow("current version not install try jvm inst %s %s first", ns[1], ns[0])
+	}
+}
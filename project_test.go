@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSplitVendorVersion(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"17", []string{"17"}},
+		{"17.0.10", []string{"17"}},
+		{"1.8.0_402", []string{"8"}},
+		{"17.0.10-librca", []string{"17", "liberica"}},
+		{"21.0.2-graalce", []string{"21", "graal"}},
+		{"liberica64-17.0.10", []string{"17", "liberica"}},
+		{"openjdk-17", []string{"17", "openjdk"}},
+		{"OpenJDK64-11.0.2", []string{"11", "openjdk"}},
+		{"temurin-17.0.9+9", []string{"17", "temurin"}},
+		{"17.0.9-tem", []string{"17", "tem"}},
+		{"system", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := splitVendorVersion(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitVendorVersion(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseVersionFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{projectFile, "17 liberica\n", []string{"17", "liberica"}},
+		{projectFile, "# pinned\n\n21\n", []string{"21"}},
+		{".java-version", "17.0.10\n", []string{"17"}},
+		{".java-version", "openjdk64-11.0.2\n", []string{"11", "openjdk"}},
+		{".sdkmanrc", "# sdk env\ngradle=8.5\njava = 17.0.10-librca\n", []string{"17", "liberica"}},
+		{".sdkmanrc", "gradle=8.5\n", nil},
+		{".tool-versions", "nodejs 20.11.0\njava liberica-21.0.2+14\n", []string{"21", "liberica"}},
+		{".tool-versions", "java temurin-17.0.9+9\n", []string{"17", "temurin"}},
+		{".tool-versions", "nodejs 20.11.0\n", nil},
+		{".tool-versions", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := filepath.Join(t.TempDir(), tt.name)
+			if err := os.WriteFile(p, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if got := parseVersionFile(p); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseVersionFile(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindProjectVersion(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// 上层目录的 .java-version 被下层的 .tool-versions 覆盖，没有 java 的版本文件被跳过
+	files := map[string]string{
+		filepath.Join(root, ".java-version"):       "11\n",
+		filepath.Join(root, "a", ".tool-versions"): "java openjdk-17\n",
+		filepath.Join(sub, ".tool-versions"):       "nodejs 20.11.0\n",
+	}
+	for p, c := range files {
+		if err := os.WriteFile(p, []byte(c), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, file := findProjectVersion(sub)
+	if want := []string{"17", "openjdk"}; !reflect.DeepEqual(got, want) || file != filepath.Join(root, "a", ".tool-versions") {
+		t.Errorf("findProjectVersion = %q %s", got, file)
+	}
+}
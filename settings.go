@@ -0,0 +1,349 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/fatih/color"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// setting 一个可以通过 jvm config 修改的配置项
+type setting struct {
+	key  string
+	kind string
+	// enum enum 的可选值，url 允许的协议
+	enum []string
+	def  string
+	desc string
+	// project 可以由项目的 .jvm.json 设置，决定下载来源和安装位置的配置项不能由clone下来的项目修改
+	project bool
+}
+
+var settings = []setting{
+	{key: "default-vendor", kind: "enum", enum: []string{"liberica", "openjdk", "oracle", "graal"}, def: "liberica", desc: "vendor used when a command gets no vendor", project: true},
+	{key: "install-dir", kind: "path", desc: "directory jdks are installed into, default <data dir>/jdks"},
+	{key: "mirror", kind: "url", enum: []string{"http", "https"}, desc: "base url replacing the vendor download host, e.g. https://mirror.example.com/jdk"},
+	{key: "proxy", kind: "url", enum: []string{"http", "https", "socks5"}, desc: "http(s) or socks5 proxy used for downloads, default from HTTPS_PROXY/HTTP_PROXY"},
+	{key: "cache-size", kind: "int", def: "1024", desc: "MB of downloaded archives kept for reinstalls, 0 keeps none"},
+	{key: "color", kind: "enum", enum: []string{"auto", "always", "never"}, def: "auto", desc: "colored output", project: true},
+	{key: "lock-timeout", kind: "int", def: "300", desc: "seconds to wait for another jvm process holding a lock, 0 fails at once"},
+	{key: "auto-switch", kind: "bool", def: "true", desc: "switch jdk on cd when the shell integration [jvm init] is loaded", project: true},
+}
+
+// projectConfigFile 项目级配置文件，从当前目录向上查找，格式与用户配置相同
+const projectConfigFile = ".jvm.json"
+
+func findSetting(key string) (setting, bool) {
+	for _, s := range settings {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+// settingEnv 配置项对应的环境变量，如 default-vendor 对应 JVM_DEFAULT_VENDOR
+func settingEnv(key string) string {
+	return "JVM_" + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+}
+
+func systemConfigFile() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("ProgramData"), "jvm", "config.json")
+	}
+	return filepath.Join("/etc", "jvm", "config.json")
+}
+
+func findProjectConfig() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		if p := filepath.Join(dir, projectConfigFile); pathExist(p) {
+			return p
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// fileSettings 读取配置文件中的 settings，文件不存在或格式错误时为空
+func fileSettings(path string) map[string]string {
+	var c Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	if err = json.Unmarshal(data, &c); err != nil {
+		color.Yellow("ignore %s:%s", path, err)
+		return nil
+	}
+	return c.Settings
+}
+
+// projectSettings 可以由项目配置设置的配置项
+func projectSettings() []string {
+	var ks []string
+	for _, s := range settings {
+		if s.project {
+			ks = append(ks, s.key)
+		}
+	}
+	return ks
+}
+
+// warnedSettings 已经提示过的配置问题，每个只提示一次
+var warnedSettings = map[string]bool{}
+
+// warnSetting 提示被忽略的配置，输出到 stderr 避免混入命令的输出
+func warnSetting(format string, a ...interface{}) {
+	msg := fmt.Sprintf(format, a...)
+	if !warnedSettings[msg] {
+		warnedSettings[msg] = true
+		color.New(color.FgYellow).Fprintln(color.Error, msg)
+	}
+}
+
+// check 检查来自 src 的值，无效时提示并返回 false
+func (s setting) check(v, src string) (string, bool) {
+	v, err := s.validate(v)
+	if err != nil {
+		warnSetting("ignore %s from %s:%s", s.key, src, err)
+		return "", false
+	}
+	return v, true
+}
+
+// resolveSetting 按 环境变量 > 项目配置 > 用户配置 > 系统配置 > 默认值 的顺序取配置项，返回值和来源，
+// 每一层的值都与 jvm config set 一样检查，无效的值提示后跳过
+func resolveSetting(key string) (string, string) {
+	s, _ := findSetting(key)
+	if v, ok := os.LookupEnv(settingEnv(key)); ok {
+		if v, ok := s.check(v, settingEnv(key)); ok {
+			return v, settingEnv(key)
+		}
+	}
+	if p := findProjectConfig(); p != "" {
+		if v, ok := fileSettings(p)[key]; ok {
+			if !s.project {
+				warnSetting("ignore %s in %s, a project can only set %s", key, p, strings.Join(projectSettings(), "|"))
+			} else if v, ok := s.check(v, p); ok {
+				return v, p
+			}
+		}
+	}
+	if v, ok := config.Settings[key]; ok {
+		if v, ok := s.check(v, configFile()); ok {
+			return v, configFile()
+		}
+	}
+	if v, ok := fileSettings(systemConfigFile())[key]; ok {
+		if v, ok := s.check(v, systemConfigFile()); ok {
+			return v, systemConfigFile()
+		}
+	}
+	return s.def, "default"
+}
+
+func getSetting(key string) string {
+	v, _ := resolveSetting(key)
+	return v
+}
+
+func getBoolSetting(key string) bool {
+	v, err := strconv.ParseBool(getSetting(key))
+	if err != nil {
+		s, _ := findSetting(key)
+		v, _ = strconv.ParseBool(s.def)
+	}
+	return v
+}
+
+func getIntSetting(key string) int {
+	v, err := strconv.Atoi(getSetting(key))
+	if err != nil {
+		s, _ := findSetting(key)
+		v, _ = strconv.Atoi(s.def)
+	}
+	return v
+}
+
+// validate 按配置项类型检查并规范化值，没有默认值的配置项可以设为空
+func (s setting) validate(v string) (string, error) {
+	if v == "" && s.def == "" {
+		return v, nil
+	}
+	switch s.kind {
+	case "enum":
+		if !contains(s.enum, v) {
+			return "", fmt.Errorf("%s must be one of %s", s.key, strings.Join(s.enum, "|"))
+		}
+	case "bool":
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return "", fmt.Errorf("%s must be true or false", s.key)
+		}
+		v = strconv.FormatBool(b)
+	case "int":
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("%s must be a non-negative number", s.key)
+		}
+	case "url":
+		u, err := url.Parse(v)
+		if err != nil || !contains(s.enum, u.Scheme) || u.Host == "" {
+			return "", fmt.Errorf("%s must be a url with scheme %s", s.key, strings.Join(s.enum, "|"))
+		}
+	case "path":
+		if strings.HasPrefix(v, "~") {
+			hd, _ := os.UserHomeDir()
+			v = filepath.Join(hd, v[1:])
+		}
+		abs, err := filepath.Abs(v)
+		if err != nil {
+			return "", err
+		}
+		v = abs
+	}
+	return v, nil
+}
+
+// applySettings 应用需要在启动时生效的配置
+func applySettings() {
+	switch getSetting("color") {
+	case "always":
+		color.NoColor = false
+	case "never":
+		color.NoColor = true
+	}
+}
+
+// httpClient 返回使用 proxy 配置的http客户端
+func httpClient() *http.Client {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	if p := getSetting("proxy"); p != "" {
+		if u, err := url.Parse(p); err == nil {
+			tr.Proxy = http.ProxyURL(u)
+		}
+	}
+	return &http.Client{Transport: tr}
+}
+
+// mirrorURL 用 mirror 配置替换下载地址的协议和主机部分
+func mirrorURL(raw string) string {
+	m := getSetting("mirror")
+	if m == "" {
+		return raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return strings.TrimRight(m, "/") + u.Path
+}
+
+func cachePath() string {
+	return cacheRoot
+}
+
+// pruneCache 从最旧的开始删除缓存的安装包，直到总大小不超过 limit 字节，
+// 安装包名以jdk的key开头，其他进程持有该jdk的安装锁时可能正在解压，跳过
+func pruneCache(limit int64) {
+	entries, err := os.ReadDir(cachePath())
+	if err != nil {
+		return
+	}
+	var infos []os.FileInfo
+	for _, e := range entries {
+		if info, err := e.Info(); err == nil && !e.IsDir() && !strings.HasSuffix(e.Name(), ".part") {
+			infos = append(infos, info)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().After(infos[j].ModTime())
+	})
+	var total int64
+	for _, info := range infos {
+		total += info.Size()
+		if total <= limit {
+			continue
+		}
+		unlock, ok := tryLock("install-" + strings.SplitN(info.Name(), "-", 2)[0])
+		if !ok {
+			continue
+		}
+		os.Remove(filepath.Join(cachePath(), info.Name()))
+		unlock()
+	}
+}
+
+func configList(subs []string) {
+	for _, s := range settings {
+		v, src := resolveSetting(s.key)
+		color.New(color.FgCyan).Printf("  %-15s", s.key)
+		color.White(" = %s (%s)", v, src)
+		color.White("  %-15s   %s, env %s", "", s.desc, settingEnv(s.key))
+	}
+}
+
+// configKey 检查参数中的配置项名称
+func configKey(subs []string, n int, usage string) (setting, bool) {
+	if len(subs) < n {
+		usagef("usage: %s", usage)
+		return setting{}, false
+	}
+	s, ok := findSetting(subs[0])
+	if !ok {
+		usagef("unknown config key:%s, use [jvm config list] for all keys", subs[0])
+	}
+	return s, ok
+}
+
+func configGet(subs []string) {
+	s, ok := configKey(subs, 1, "jvm config get <key>")
+	if !ok {
+		return
+	}
+	fmt.Println(getSetting(s.key))
+}
+
+func configSet(subs []string) {
+	s, ok := configKey(subs, 2, "jvm config set <key> <value>")
+	if !ok {
+		return
+	}
+	v, err := s.validate(subs[1])
+	if err != nil {
+		usagef("%s", err)
+		return
+	}
+	if config.Settings == nil {
+		config.Settings = map[string]string{}
+	}
+	config.Settings[s.key] = v
+	color.Green("%s = %s", s.key, v)
+	if _, src := resolveSetting(s.key); src != configFile() {
+		color.Yellow("overridden by %s", src)
+	}
+}
+
+func configUnset(subs []string) {
+	s, ok := configKey(subs, 1, "jvm config unset <key>")
+	if !ok {
+		return
+	}
+	delete(config.Settings, s.key)
+	v, src := resolveSetting(s.key)
+	color.Green("%s = %s (%s)", s.key, v, src)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"github.com/dtdyq/jvm/local"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveSetting(t *testing.T) {
+	dir := t.TempDir()
+	project := filepath.Join(dir, projectConfigFile)
+	if err := os.WriteFile(project, []byte(`{"settings":{"default-vendor":"openjdk","mirror":"https://mirror.example.com","auto-switch":"maybe"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	defer func(s map[string]string) { config.Settings = s }(config.Settings)
+	config.Settings = map[string]string{"mirror": "https://user.example.com", "cache-size": "-1"}
+
+	tests := []struct {
+		key, env      string
+		want, wantSrc string
+	}{
+		{"default-vendor", "", "openjdk", project},
+		{"default-vendor", "graal", "graal", settingEnv("default-vendor")},
+		{"default-vendor", "foo", "openjdk", project},
+		{"mirror", "", "https://user.example.com", configFile()},
+		{"mirror", "ftp://x", "https://user.example.com", configFile()},
+		{"auto-switch", "", "true", "default"},
+		{"auto-switch", "0", "false", settingEnv("auto-switch")},
+		{"cache-size", "", "1024", "default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.env, func(t *testing.T) {
+			if tt.env != "" {
+				t.Setenv(settingEnv(tt.key), tt.env)
+			}
+			v, src := resolveSetting(tt.key)
+			if v != tt.want || src != tt.wantSrc {
+				t.Errorf("resolveSetting(%s) = %s (%s), want %s (%s)", tt.key, v, src, tt.want, tt.wantSrc)
+			}
+		})
+	}
+}
+
+func TestSettingValidate(t *testing.T) {
+	tests := []struct {
+		key, v  string
+		wantErr bool
+	}{
+		{"mirror", "https://mirror.example.com/jdk", false},
+		{"mirror", "socks5://127.0.0.1:1080", true},
+		{"mirror", "ftp://mirror.example.com", true},
+		{"mirror", "", false},
+		{"proxy", "http://127.0.0.1:8080", false},
+		{"proxy", "socks5://127.0.0.1:1080", false},
+		{"proxy", "127.0.0.1:8080", true},
+		{"default-vendor", "local", true},
+		{"cache-size", "-1", true},
+		{"auto-switch", "0", false},
+	}
+	for _, tt := range tests {
+		s, _ := findSetting(tt.key)
+		if _, err := s.validate(tt.v); (err != nil) != tt.wantErr {
+			t.Errorf("validate %s=%q err = %v, wantErr %v", tt.key, tt.v, err, tt.wantErr)
+		}
+	}
+}
+
+func TestPruneCacheSkipsLockedInstalls(t *testing.T) {
+	defer func(w, c string) { workPath, cacheRoot = w, c }(workPath, cacheRoot)
+	dir := t.TempDir()
+	workPath, cacheRoot = dir, filepath.Join(dir, "cache")
+	if err := os.MkdirAll(cacheRoot, 0755); err != nil {
+		t.Fatal(err)
+	}
+	busy := filepath.Join(cacheRoot, "liberica_17_linux_x64-bellsoft-jdk17.0.10+13-linux-amd64.tar.gz")
+	idle := filepath.Join(cacheRoot, "liberica_21_linux_x64-bellsoft-jdk21.0.2+14-linux-amd64.tar.gz")
+	for _, p := range []string{busy, idle} {
+		if err := os.WriteFile(p, []byte("archive"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 模拟另一个进程正在安装 busy 对应的jdk
+	if err := os.MkdirAll(filepath.Dir(lockPath("install-liberica_17_linux_x64")), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(lockPath("install-liberica_17_linux_x64"), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err = local.TryLock(f); err != nil {
+		t.Fatal(err)
+	}
+	defer local.Unlock(f)
+
+	pruneCache(0)
+	if !pathExist(busy) {
+		t.Errorf("archive being installed by another process was removed")
+	}
+	if pathExist(idle) {
+		t.Errorf("idle archive over the limit was kept")
+	}
+	if len(heldLocks) != 0 {
+		t.Errorf("locks left held: %v", heldLocks)
+	}
+}
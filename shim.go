@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"github.com/dtdyq/jvm/local"
+	"github.com/fatih/color"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// shimsPath 存放 java、javac 等启动器的目录，需要排在PATH中所有jdk之前
+func shimsPath() string {
+	return filepath.Join(dataPath, "shims")
+}
+
+// shimName 判断当前是否通过shim调用，返回被调用的程序名和它的参数
+func shimName(args []string) (string, []string) {
+	// unix下shim是指向jvm的符号链接，argv[0] 即为 java、javac 等程序名
+	if name := filepath.Base(args[0]); name != "jvm" && pathExist(filepath.Join(shimsPath(), name)) {
+		return name, args[1:]
+	}
+	// windows下shim是调用 jvm shim <name> 的批处理
+	if len(args) > 2 && args[1] == "shim" {
+		return args[2], args[3:]
+	}
+	return "", nil
+}
+
+// setEnv 设置环境变量列表中的 k，已存在时替换
+func setEnv(env []string, k, v string) []string {
+	var ret []string
+	for _, e := range env {
+		if !strings.HasPrefix(strings.ToUpper(e), strings.ToUpper(k)+"=") {
+			ret = append(ret, e)
+		}
+	}
+	return append(ret, k+"="+v)
+}
+
+// runShim 按 JVM_VERSION > 项目版本文件 > 全局激活 找到jdk，直接执行其中的同名程序
+func runShim(name string, args []string) {
+	color.Output = color.Error
+	home, _ := resolveHome()
+	if home == "" {
+		color.Red("jvm: no jdk selected for %s, use [jvm use <version>] or [jvm local <version>]", name)
+		os.Exit(127)
+	}
+	bin := filepath.Join(home, "bin", name)
+	if runtime.GOOS == "windows" {
+		bin += ".exe"
+	}
+	if !pathExist(bin) {
+		color.Red("jvm: %s not found in %s", name, home)
+		os.Exit(127)
+	}
+	err := local.Exec(bin, args, setEnv(os.Environ(), "JAVA_HOME", home))
+	color.Red("jvm: exec %s fail:%s", bin, err)
+	os.Exit(126)
+}
+
+// rehash 根据所有已安装jdk的bin目录重新生成shims
+func rehash() (int, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+	if self, err = filepath.EvalSymlinks(self); err != nil {
+		return 0, err
+	}
+	names := map[string]bool{}
+	for _, key := range installedJdks() {
+		entries, _ := os.ReadDir(filepath.Join(jdkPath, key, "bin"))
+		for _, e := range entries {
+			n := e.Name()
+			if runtime.GOOS == "windows" {
+				// bin下还有dll，只处理可执行文件
+				if !strings.HasSuffix(n, ".exe") {
+					continue
+				}
+				n = strings.TrimSuffix(n, ".exe")
+			}
+			names[n] = true
+		}
+	}
+	dir := shimsPath()
+	if err = os.RemoveAll(dir); err != nil {
+		return 0, err
+	}
+	if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+		return 0, err
+	}
+	for n := range names {
+		if runtime.GOOS == "windows" {
+			err = os.WriteFile(filepath.Join(dir, n+".cmd"), []byte(fmt.Sprintf("@\"%s\" shim %s %%*\r\n", self, n)), 0755)
+		} else {
+			err = os.Symlink(self, filepath.Join(dir, n))
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(names), nil
+}
+
+func rehashJdk(subs []string) {
+	n, err := rehash()
+	if err != nil {
+		failf("rehash fail:%s", err)
+		return
+	}
+	color.Green("%d shims written to %s", n, shimsPath())
+}
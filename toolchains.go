@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"github.com/dtdyq/jvm/local"
+	"github.com/fatih/color"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const xmlBegin = "<!-- >>> jvm >>> -->"
+const xmlEnd = "<!-- <<< jvm <<< -->"
+
+// installedChanged 在安装或删除jdk后更新shims和已接入的构建工具配置
+func installedChanged() {
+	if _, err := rehash(); err != nil {
+		color.Red("rehash fail:%s", err)
+	}
+	if p := mavenToolchainsPath(); strings.Contains(readText(p), xmlBegin) {
+		if err := writeMavenToolchains(p); err != nil {
+			color.Red("update %s fail:%s", p, err)
+		}
+	}
+	if p := gradlePropertiesPath(); strings.Contains(readText(p), local.BlockBegin) {
+		noDownload := strings.Contains(readText(p), gradleAutoDownload+"=false")
+		if err := writeGradleProperties(p, noDownload); err != nil {
+			color.Red("update %s fail:%s", p, err)
+		}
+	}
+	syncIdes()
+}
+
+func readText(path string) string {
+	data, _ := os.ReadFile(path)
+	return string(data)
+}
+
+// replaceManaged 用 body 替换 begin/end 之间由jvm管理的内容，没有时插入到 before 之前(before 为空时追加到末尾)
+func replaceManaged(content, begin, end, body, before string) (string, error) {
+	block := begin + "\n" + body + end
+	if b := strings.Index(content, begin); b >= 0 {
+		e := strings.Index(content[b:], end)
+		if e < 0 {
+			return "", fmt.Errorf("found %s without %s", begin, end)
+		}
+		return content[:b] + block + content[b+e+len(end):], nil
+	}
+	if before == "" {
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		return content + block + "\n", nil
+	}
+	i := strings.LastIndex(content, before)
+	if i < 0 {
+		return "", fmt.Errorf("%s not found", before)
+	}
+	// 保持与结束标签相同的缩进
+	ls := strings.LastIndex(content[:i], "\n") + 1
+	return content[:ls] + "  " + block + "\n" + content[ls:], nil
+}
+
+// writeManaged 写入由jvm管理的配置文件，managed 表示文件中已有jvm写入的内容，第一次接管已有文件时先备份
+func writeManaged(path, old, content string, managed bool) error {
+	if old == content {
+		return nil
+	}
+	if old != "" && !managed {
+		bak, err := local.BackupFile(path)
+		if err != nil {
+			return err
+		}
+		color.White("backup %s to %s", path, bak)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(content), 0644)
+}
+
+func mavenToolchainsPath() string {
+	dir, _ := os.UserHomeDir()
+	return filepath.Join(dir, ".m2", "toolchains.xml")
+}
+
+// writeMavenToolchains 为每个已安装的jdk生成 toolchain，只替换jvm管理的部分
+func writeMavenToolchains(path string) error {
+	var b strings.Builder
+	for _, key := range installedJdks() {
+		ns := strings.Split(key, "_")
+		b.WriteString(fmt.Sprintf(`  <toolchain>
+    <type>jdk</type>
+    <provides>
+      <version>%s</version>
+      <vendor>%s</vendor>
+      <id>jvm-%s-%s</id>
+    </provides>
+    <configuration>
+      <jdkHome>%s</jdkHome>
+    </configuration>
+  </toolchain>
+`, xmlEscape(ns[1]), xmlEscape(ns[0]), xmlEscape(ns[0]), xmlEscape(ns[1]), xmlEscape(filepath.Join(jdkPath, key))))
+	}
+	old := readText(path)
+	content := old
+	if strings.TrimSpace(content) == "" {
+		content = "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<toolchains>\n</toolchains>\n"
+	}
+	content, err := replaceManaged(content, xmlBegin, xmlEnd, b.String()+"  ", "</toolchains>")
+	if err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	return writeManaged(path, old, content, strings.Contains(old, xmlBegin))
+}
+
+const gradlePaths = "org.gradle.java.installations.paths"
+const gradleAutoDownload = "org.gradle.java.installations.auto-download"
+
+func gradlePropertiesPath() string {
+	if gh := os.Getenv("GRADLE_USER_HOME"); gh != "" {
+		return filepath.Join(gh, "gradle.properties")
+	}
+	dir, _ := os.UserHomeDir()
+	return filepath.Join(dir, ".gradle", "gradle.properties")
+}
+
+// userGradlePaths 返回jvm配置块之外用户自己设置的 org.gradle.java.installations.paths
+func userGradlePaths(content string) []string {
+	if b := strings.Index(content, local.BlockBegin); b >= 0 {
+		if e := strings.Index(content[b:], local.BlockEnd); e >= 0 {
+			content = content[:b] + content[b+e+len(local.BlockEnd):]
+		}
+	}
+	var ps []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, gradlePaths) {
+			continue
+		}
+		v := strings.TrimLeft(line[len(gradlePaths):], " \t")
+		if v == "" || (v[0] != '=' && v[0] != ':') {
+			continue
+		}
+		for _, p := range strings.Split(v[1:], ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				ps = append(ps, p)
+			}
+		}
+	}
+	return ps
+}
+
+// writeGradleProperties 让gradle的toolchain自动检测覆盖所有已安装的jdk，noDownload 时关闭gradle自行下载jdk
+func writeGradleProperties(path string, noDownload bool) error {
+	var homes []string
+	for _, key := range installedJdks() {
+		// properties 文件中反斜杠是转义符，统一使用 /
+		homes = append(homes, filepath.ToSlash(filepath.Join(jdkPath, key)))
+	}
+	old := readText(path)
+	// 配置块在文件末尾，后出现的值生效，把用户自己设置的路径合并进来避免被覆盖
+	for _, p := range userGradlePaths(old) {
+		if !contains(homes, p) {
+			homes = append(homes, p)
+		}
+	}
+	body := gradlePaths + "=" + strings.Join(homes, ",") + "\n"
+	if noDownload {
+		body += gradleAutoDownload + "=false\n"
+	}
+	content, err := replaceManaged(old, local.BlockBegin, local.BlockEnd, body, "")
+	if err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	return writeManaged(path, old, content, strings.Contains(old, local.BlockBegin))
+}
+
+// toolchainsMaven 把已安装的jdk写入maven的 toolchains.xml，之后安装jdk时自动更新
+func toolchainsMaven(subs []string) {
+	p := mavenToolchainsPath()
+	if err := writeMavenToolchains(p); err != nil {
+		failf("update %s fail:%s", p, err)
+		return
+	}
+	color.Green("%d jdk(s) written to %s", len(installedJdks()), p)
+}
+
+// toolchainsGradle 把已安装的jdk写入gradle的 gradle.properties，之后安装jdk时自动更新
+func toolchainsGradle(subs []string) {
+	p := gradlePropertiesPath()
+	if err := writeGradleProperties(p, flagNoDownload); err != nil {
+		failf("update %s fail:%s", p, err)
+		return
+	}
+	color.Green("%d jdk(s) written to %s", len(installedJdks()), p)
+}
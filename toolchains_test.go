@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUserGradlePaths(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"none", "org.gradle.jvmargs=-Xmx2g\n", nil},
+		{"user paths", "org.gradle.java.installations.paths=/opt/jdk8, /opt/jdk11\n", []string{"/opt/jdk8", "/opt/jdk11"}},
+		{"colon and spaces", "  org.gradle.java.installations.paths : /opt/jdk8\n", []string{"/opt/jdk8"}},
+		{"commented", "#org.gradle.java.installations.paths=/opt/jdk8\n", nil},
+		{"other key", "org.gradle.java.installations.paths.extra=/opt/jdk8\n", nil},
+		{"jvm block skipped", "org.gradle.java.installations.paths=/opt/jdk8\n# >>> jvm >>>\norg.gradle.java.installations.paths=/jvm/jdk17\n# <<< jvm <<<\n", []string{"/opt/jdk8"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := userGradlePaths(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteMavenToolchainsEscapes(t *testing.T) {
+	defer func(p string) { jdkPath = p }(jdkPath)
+	dir := t.TempDir()
+	jdkPath = filepath.Join(dir, "R&D <jdks>")
+	if err := os.MkdirAll(filepath.Join(jdkPath, "liberica_17_linux_x64", "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(dir, "toolchains.xml")
+	if err := writeMavenToolchains(p); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Homes []string `xml:"toolchain>configuration>jdkHome"`
+	}
+	if err = xml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("invalid toolchains.xml: %s\n%s", err, data)
+	}
+	if want := filepath.Join(jdkPath, "liberica_17_linux_x64"); len(doc.Homes) != 1 || doc.Homes[0] != want {
+		t.Errorf("jdkHome = %q, want %q", doc.Homes, want)
+	}
+	if strings.Contains(string(data), "R&D") {
+		t.Errorf("& not escaped:\n%s", data)
+	}
+}
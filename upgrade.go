@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"github.com/dtdyq/jvm/local"
+	"github.com/fatih/color"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// outdatedJdk 目录中有更新补丁版本的已安装jdk
+type outdatedJdk struct {
+	Key       string `json:"key"`
+	Vendor    string `json:"vendor"`
+	Major     string `json:"major"`
+	Installed string `json:"installed"`
+	Available string `json:"available"`
+	URL       string `json:"url"`
+}
+
+var catalogVersionRe = regexp.MustCompile(`\d+(?:u\d+)?(?:\.\d+)*(?:\+\d+)?`)
+
+// catalogVersion 从下载地址的文件名中解析出版本，如 bellsoft-jdk17.0.10+13-linux-amd64.tar.gz 为 17.0.10+13
+func catalogVersion(url string) string {
+	return catalogVersionRe.FindString(path.Base(url))
+}
+
+// versionParts 把 17.0.10+13、8u402、1.8.0_402 这类版本转换为可以比较的数字，忽略构建号
+func versionParts(v string) []int {
+	v = strings.TrimPrefix(strings.SplitN(v, "+", 2)[0], "1.")
+	v = strings.Replace(v, "u", ".0.", 1)
+	var ns []int
+	for _, f := range strings.FieldsFunc(v, func(r rune) bool { return r == '.' || r == '_' || r == '-' }) {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			break
+		}
+		ns = append(ns, n)
+	}
+	return ns
+}
+
+// newerVersion a 是否比 b 新，缺少的部分按 0 比较
+func newerVersion(a, b string) bool {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			return x > y
+		}
+	}
+	return false
+}
+
+// findOutdated 返回 keys 中目录里有更新补丁版本的jdk，接管的jdk不在目录中，读取不到安装版本的jdk无法比较，跳过
+func findOutdated(keys []string) []outdatedJdk {
+	ret := []outdatedJdk{}
+	for _, k := range keys {
+		url, ok := jdks[k]
+		if !ok {
+			continue
+		}
+		inst, ok := installedVersion(k)
+		if !ok {
+			color.Yellow("installed version of %s is unknown, skipped, use [jvm inst %s --force] to reinstall it", jdkLabel(k), strings.Split(k, "_")[1]+" "+strings.Split(k, "_")[0])
+			continue
+		}
+		avail := catalogVersion(url)
+		if !newerVersion(avail, inst) {
+			continue
+		}
+		ns := strings.Split(k, "_")
+		ret = append(ret, outdatedJdk{Key: k, Vendor: ns[0], Major: ns[1], Installed: inst, Available: strings.SplitN(avail, "+", 2)[0], URL: mirrorURL(url)})
+	}
+	return ret
+}
+
+// upgradeTargets 按 [version|alias] [vendor] 筛选已安装的jdk，只给版本时包括该版本的所有厂商
+func upgradeTargets(subs []string) ([]string, bool) {
+	subs, ok := expandSelection(subs, installedJdks())
+	if !ok {
+		failCode(exitNotInstalled, "no installed jdk matches %s", strings.Join(subs, " "))
+		return nil, false
+	}
+	vendor := flagVendor
+	if len(subs) > 1 {
+		vendor = subs[1]
+	}
+	if len(subs) > 0 && !contains(supportVersion, subs[0]) {
+		usagef("un support version:%s, one of %s", subs[0], strings.Join(supportVersion, "|"))
+		return nil, false
+	}
+	if vendor != "" && !contains(supportVendor, vendor) {
+		usagef("un support jdk type:%s, one of %s", vendor, strings.Join(supportVendor, "|"))
+		return nil, false
+	}
+	var keys []string
+	for _, k := range installedJdks() {
+		ns := strings.Split(k, "_")
+		if (len(subs) == 0 || ns[1] == subs[0]) && (vendor == "" || ns[0] == vendor) {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 && (len(subs) > 0 || vendor != "") {
+		failCode(exitNotInstalled, "no installed jdk matches %s", strings.TrimSpace(strings.Join(subs, " ")+" "+flagVendor))
+		return nil, false
+	}
+	return keys, true
+}
+
+// outdatedJdks 列出 jvm upgrade 会升级的jdk
+func outdatedJdks(subs []string) {
+	keys, ok := upgradeTargets(subs)
+	if !ok {
+		return
+	}
+	outs := findOutdated(keys)
+	if jsonOutput() {
+		printJSON("outdated", map[string]interface{}{"jdks": outs})
+		return
+	}
+	if len(outs) == 0 {
+		color.Green("installed jdks are up to date")
+		return
+	}
+	for _, o := range outs {
+		color.Blue("  %s [%s] %s -> %s", o.Major, o.Vendor, o.Installed, o.Available)
+	}
+	color.White("use [jvm upgrade] to install them")
+}
+
+// retargetAliases 把写着旧补丁版本的别名改为新版本，如 17.0.10-liberica 改为 17.0.11-liberica
+func retargetAliases(key, from, to string) {
+	if from == to {
+		return
+	}
+	re := regexp.MustCompile(`(^|[\s-])` + regexp.QuoteMeta(from) + `(?:\+\d+)?($|[\s-])`)
+	for _, name := range aliasesOf(key) {
+		t := config.Aliases[name]
+		if !re.MatchString(t) {
+			continue
+		}
+		config.Aliases[name] = re.ReplaceAllString(t, "${1}"+to+"${2}")
+		color.Green("alias %s -> %s", name, config.Aliases[name])
+	}
+}
+
+// supersededPath 返回 --keep 保留的旧补丁版本的目录，不是有效的key，不会被当作已安装的jdk
+func supersededPath(key, version string) string {
+	return filepath.Join(jdkPath, "superseded", key+"-"+version)
+}
+
+// keepSuperseded 把旧补丁版本移到 supersededPath，新版本安装失败时用返回的函数移回
+func keepSuperseded(o outdatedJdk) (func(), error) {
+	sp, kept := filepath.Join(jdkPath, o.Key), supersededPath(o.Key, o.Installed)
+	if pathExist(kept) {
+		return nil, fmt.Errorf("%s already exists, remove it first", kept)
+	}
+	if err := os.MkdirAll(filepath.Dir(kept), os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(sp, kept); err != nil {
+		return nil, fmt.Errorf("move %s to %s fail:%s", sp, kept, err)
+	}
+	os.Rename(manifestPath(o.Key), kept+".json")
+	return func() {
+		if !pathExist(sp) {
+			os.Rename(kept, sp)
+			os.Rename(kept+".json", manifestPath(o.Key))
+		}
+	}, nil
+}
+
+// upgradeJdk 把已安装的jdk升级到目录中更新的补丁版本，同一厂商和主版本只安装一个补丁版本，
+// 旧版本被替换，--keep 时移到 supersededPath 保留
+func upgradeJdk(subs []string) {
+	keys, ok := upgradeTargets(subs)
+	if !ok {
+		return
+	}
+	outs := findOutdated(keys)
+	if len(outs) == 0 && !jsonOutput() {
+		color.Green("installed jdks are up to date")
+		return
+	}
+	done := []outdatedJdk{}
+	for _, o := range outs {
+		// 替换正在运行的jdk会让进程加载到新旧混合的文件
+		dir := filepath.Join(jdkPath, o.Key)
+		if p, err := filepath.EvalSymlinks(dir); err == nil {
+			dir = p
+		}
+		if ps, _ := local.ProcessesUnder(dir); len(ps) > 0 && !flagForce {
+			color.Yellow("%s [%s] %s is used by %d running process(es), skipped, stop them or use --force", o.Major, o.Vendor, o.Installed, len(ps))
+			continue
+		}
+		color.White("upgrade %s [%s] %s -> %s", o.Major, o.Vendor, o.Installed, o.Available)
+		restore := func() {}
+		if flagKeep {
+			var err error
+			if restore, err = keepSuperseded(o); err != nil {
+				failErr(err)
+				continue
+			}
+		}
+		if err := installJdk(o.Key); err != nil {
+			restore()
+			failErr(err)
+			continue
+		}
+		if flagKeep {
+			color.Green("%s kept in %s, it is not managed by jvm, delete it when no longer needed", o.Installed, supersededPath(o.Key, o.Installed))
+		}
+		to := jdkFullVersion(o.Key)
+		if err := withStateLock(func() { retargetAliases(o.Key, o.Installed, to) }); err != nil {
+			failErr(err)
+		}
+		if o.Key == config.Active {
+			color.Green("active jdk is now %s", jdkLabel(o.Key))
+		}
+		o.Available = to
+		done = append(done, o)
+	}
+	if jsonOutput() {
+		printJSON("upgrade", map[string]interface{}{"jdks": done})
+	}
+}
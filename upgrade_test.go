@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestVersionParts(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []int
+	}{
+		{"17.0.10+13", []int{17, 0, 10}},
+		{"17.0.10", []int{17, 0, 10}},
+		{"8u402+7", []int{8, 0, 402}},
+		{"8u402", []int{8, 0, 402}},
+		{"1.8.0_402", []int{8, 0, 402}},
+		{"21", []int{21}},
+		{"11.0.22-ea", []int{11, 0, 22}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := versionParts(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("versionParts(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNewerVersion(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"17.0.11+9", "17.0.10", true},
+		{"17.0.10+13", "17.0.10", false},
+		{"17.0.10", "17.0.11", false},
+		{"17.0.1", "17", true},
+		{"17", "17.0.0", false},
+		{"8u412+9", "1.8.0_402", true},
+		{"8u402+7", "1.8.0_402", false},
+		{"17.0.10", "17.0.9", true},
+	}
+	for _, tt := range tests {
+		if got := newerVersion(tt.a, tt.b); got != tt.want {
+			t.Errorf("newerVersion(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCatalogVersion(t *testing.T) {
+	tests := []struct{ url, want string }{
+		{"https://download.bell-sw.com/java/17.0.10+13/bellsoft-jdk17.0.10+13-linux-amd64.tar.gz", "17.0.10+13"},
+		{"https://download.bell-sw.com/java/8u402+7/bellsoft-jdk8u402+7-windows-amd64.zip", "8u402+7"},
+		{"https://download.java.net/java/GA/jdk21.0.2/f2283984656d49e1b8/13/GPL/openjdk-21.0.2_linux-x64_bin.tar.gz", "21.0.2"},
+		{"https://download.oracle.com/java/17/archive/jdk-17.0.10_macos-aarch64_bin.tar.gz", "17.0.10"},
+	}
+	for _, tt := range tests {
+		if got := catalogVersion(tt.url); got != tt.want {
+			t.Errorf("catalogVersion(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestKeepSuperseded(t *testing.T) {
+	defer func(p string) { jdkPath = p }(jdkPath)
+	jdkPath = t.TempDir()
+	o := outdatedJdk{Key: "liberica_17_linux_x64", Installed: "17.0.10"}
+	sp := filepath.Join(jdkPath, o.Key)
+	if err := os.MkdirAll(filepath.Join(sp, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(manifestPath(o.Key), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	restore, err := keepSuperseded(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept := supersededPath(o.Key, o.Installed)
+	if pathExist(sp) || !pathExist(filepath.Join(kept, "bin")) || !pathExist(kept+".json") {
+		t.Fatalf("%s not moved to %s", sp, kept)
+	}
+	if keys := installedJdks(); len(keys) != 0 {
+		t.Errorf("kept patch listed as installed: %v", keys)
+	}
+	restore()
+	if !pathExist(filepath.Join(sp, "bin")) || !pathExist(manifestPath(o.Key)) || pathExist(kept) {
+		t.Errorf("%s not restored", sp)
+	}
+	if _, err = keepSuperseded(o); err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(sp, 0755)
+	if _, err = keepSuperseded(o); err == nil {
+		t.Errorf("existing %s overwritten", kept)
+	}
+}